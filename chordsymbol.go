@@ -0,0 +1,39 @@
+package abc
+
+// ChordSymbol is a chord name written in quotes before a note, such as
+// "Am7/G", for guitar or piano accompaniment.
+//
+// Quoted text that is not a chord name, such as "N.C." or "Fine", is kept
+// as written in Text, and the other fields are left empty.
+type ChordSymbol struct {
+	Root       Pitch
+	Accidental Accidental // AccidentalSharp or AccidentalFlat, or none
+	Quality    ChordQuality
+	// Extensions holds the further notes of the chord beyond its quality,
+	// in the order written, such as "9", "b9", "#11" or "add9". A 9, 11
+	// or 13 in place of the 7 of a seventh chord is given first, so C9 is
+	// a dominant seventh with the extension "9".
+	Extensions     []string
+	Bass           Pitch      // Alternate bass note, as in "C/G", or zero
+	BassAccidental Accidental // Accidental of the bass note
+	Text           string     // Quoted text that is not a chord name
+}
+
+// ChordQuality is the basic type of a chord, determining its third, fifth
+// and seventh.
+type ChordQuality int
+
+const (
+	ChordMajor             ChordQuality = iota // C
+	ChordMinor                                 // Cm
+	ChordDominant                              // C7
+	ChordMajorSeventh                          // Cmaj7
+	ChordMinorSeventh                          // Cm7
+	ChordHalfDiminished                        // Cm7b5
+	ChordDiminished                            // Cdim
+	ChordDiminishedSeventh                     // Cdim7
+	ChordAugmented                             // Caug
+	ChordSuspendedSecond                       // Csus2
+	ChordSuspendedFourth                       // Csus4
+	ChordPower                                 // C5, the root and fifth only
+)
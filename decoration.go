@@ -0,0 +1,74 @@
+package abc
+
+// Decoration is a symbol such as a trill, fermata or dynamic marking,
+// written between exclamation marks before the note, chord or bar line it
+// applies to, as in !trill!A. Common decorations may also be written as
+// a single character, such as ~ for a roll or T for a trill.
+//
+// A decoration is identified by its name in the ABC standard. Names not
+// in the standard, such as those added by other software, are kept as
+// written.
+type Decoration string
+
+// The decorations of the ABC 2.1 standard. Where the standard gives more
+// than one name for a decoration, the first is used.
+const (
+	DecorationTrill           Decoration = "trill"
+	DecorationTrillStart      Decoration = "trill("
+	DecorationTrillEnd        Decoration = "trill)"
+	DecorationLowerMordent    Decoration = "lowermordent" // also !mordent!
+	DecorationUpperMordent    Decoration = "uppermordent" // also !pralltriller!
+	DecorationRoll            Decoration = "roll"
+	DecorationTurn            Decoration = "turn"
+	DecorationTurnX           Decoration = "turnx"
+	DecorationInvertedTurn    Decoration = "invertedturn"
+	DecorationInvertedTurnX   Decoration = "invertedturnx"
+	DecorationArpeggio        Decoration = "arpeggio"
+	DecorationAccent          Decoration = "accent" // also !>! and !emphasis!
+	DecorationFermata         Decoration = "fermata"
+	DecorationInvertedFermata Decoration = "invertedfermata"
+	DecorationTenuto          Decoration = "tenuto"
+	DecorationFinger0         Decoration = "0"
+	DecorationFinger1         Decoration = "1"
+	DecorationFinger2         Decoration = "2"
+	DecorationFinger3         Decoration = "3"
+	DecorationFinger4         Decoration = "4"
+	DecorationFinger5         Decoration = "5"
+	DecorationPlus            Decoration = "plus" // also !+!
+	DecorationSnap            Decoration = "snap"
+	DecorationSlide           Decoration = "slide"
+	DecorationWedge           Decoration = "wedge"
+	DecorationUpBow           Decoration = "upbow"
+	DecorationDownBow         Decoration = "downbow"
+	DecorationOpen            Decoration = "open"
+	DecorationThumb           Decoration = "thumb"
+	DecorationBreath          Decoration = "breath"
+	DecorationPPPP            Decoration = "pppp"
+	DecorationPPP             Decoration = "ppp"
+	DecorationPP              Decoration = "pp"
+	DecorationP               Decoration = "p"
+	DecorationMP              Decoration = "mp"
+	DecorationMF              Decoration = "mf"
+	DecorationF               Decoration = "f"
+	DecorationFF              Decoration = "ff"
+	DecorationFFF             Decoration = "fff"
+	DecorationFFFF            Decoration = "ffff"
+	DecorationSFZ             Decoration = "sfz"
+	DecorationCrescendoStart  Decoration = "crescendo("  // also !<(!
+	DecorationCrescendoEnd    Decoration = "crescendo)"  // also !<)!
+	DecorationDiminuendoStart Decoration = "diminuendo(" // also !>(!
+	DecorationDiminuendoEnd   Decoration = "diminuendo)" // also !>)!
+	DecorationSegno           Decoration = "segno"
+	DecorationCoda            Decoration = "coda"
+	DecorationDS              Decoration = "D.S."
+	DecorationDC              Decoration = "D.C."
+	DecorationDaCoda          Decoration = "dacoda"
+	DecorationDaCapo          Decoration = "dacapo"
+	DecorationFine            Decoration = "fine"
+	DecorationShortPhrase     Decoration = "shortphrase"
+	DecorationMediumPhrase    Decoration = "mediumphrase"
+	DecorationLongPhrase      Decoration = "longphrase"
+	DecorationStaccato        Decoration = "staccato" // written as .
+	DecorationEditorial       Decoration = "editorial"
+	DecorationCourtesy        Decoration = "courtesy"
+)
@@ -0,0 +1,100 @@
+package abc
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Duration is an exact length of time, as a fraction such as 3/16. It is
+// measured in whole notes or in unit note lengths, depending on where it
+// is used. A Duration with a zero Denominator is treated as zero.
+type Duration struct {
+	Numerator   int
+	Denominator int
+}
+
+// NewDuration returns the duration numerator/denominator in lowest terms.
+func NewDuration(numerator, denominator int) Duration {
+	return Duration{Numerator: numerator, Denominator: denominator}.Normalize()
+}
+
+// Normalize returns d in lowest terms with a positive denominator. Zero
+// is returned as 0/1.
+func (d Duration) Normalize() Duration {
+	if d.Numerator == 0 || d.Denominator == 0 {
+		return Duration{Numerator: 0, Denominator: 1}
+	}
+	if d.Denominator < 0 {
+		d.Numerator, d.Denominator = -d.Numerator, -d.Denominator
+	}
+	divisor := gcd(d.Numerator, d.Denominator)
+	return Duration{Numerator: d.Numerator / divisor, Denominator: d.Denominator / divisor}
+}
+
+// Add returns the sum d+o.
+func (d Duration) Add(o Duration) Duration {
+	d, o = d.Normalize(), o.Normalize()
+	return NewDuration(d.Numerator*o.Denominator+o.Numerator*d.Denominator, d.Denominator*o.Denominator)
+}
+
+// Sub returns the difference d-o.
+func (d Duration) Sub(o Duration) Duration {
+	o = o.Normalize()
+	return d.Add(Duration{Numerator: -o.Numerator, Denominator: o.Denominator})
+}
+
+// Mul returns the product d*o.
+func (d Duration) Mul(o Duration) Duration {
+	d, o = d.Normalize(), o.Normalize()
+	return NewDuration(d.Numerator*o.Numerator, d.Denominator*o.Denominator)
+}
+
+// Cmp compares d and o, returning -1 if d is shorter than o, 0 if they
+// are equal and +1 if d is longer.
+func (d Duration) Cmp(o Duration) int {
+	d, o = d.Normalize(), o.Normalize()
+	a, b := d.Numerator*o.Denominator, o.Numerator*d.Denominator
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether d is zero.
+func (d Duration) IsZero() bool {
+	return d.Numerator == 0 || d.Denominator == 0
+}
+
+// Float64 returns d as a floating point number, for use where exact
+// values are not needed, such as playback.
+func (d Duration) Float64() float64 {
+	if d.Denominator == 0 {
+		return 0
+	}
+	return float64(d.Numerator) / float64(d.Denominator)
+}
+
+// String returns d in lowest terms, such as 3/16, or as a whole number
+// such as 2.
+func (d Duration) String() string {
+	d = d.Normalize()
+	if d.Denominator == 1 {
+		return strconv.Itoa(d.Numerator)
+	}
+	return fmt.Sprintf("%d/%d", d.Numerator, d.Denominator)
+}
+
+// gcd returns the greatest common divisor of a and b, which must not both
+// be zero.
+func gcd(a, b int) int {
+	if a < 0 {
+		a = -a
+	}
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
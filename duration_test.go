@@ -0,0 +1,58 @@
+package abc
+
+import "testing"
+
+func TestDuration(t *testing.T) {
+	var tests = []struct {
+		name     string
+		got      Duration
+		expected Duration
+	}{
+		{"normalize", Duration{6, 8}.Normalize(), Duration{3, 4}},
+		{"normalize negative denominator", Duration{1, -2}.Normalize(), Duration{-1, 2}},
+		{"normalize zero", Duration{}.Normalize(), Duration{0, 1}},
+		{"add", NewDuration(1, 8).Add(NewDuration(1, 16)), Duration{3, 16}},
+		{"add zero", Duration{}.Add(NewDuration(1, 4)), Duration{1, 4}},
+		{"sub", NewDuration(3, 4).Sub(NewDuration(1, 8)), Duration{5, 8}},
+		{"mul triplet", NewDuration(1, 8).Mul(NewDuration(2, 3)), Duration{1, 12}},
+		{"note length", Note{Duration: NoteLength{3, 2}}.Length(), Duration{3, 2}},
+		{"chord length", Chord{Notes: []Note{{Duration: NoteLength{1, 2}}}, Duration: NoteLength{3, 1}}.Length(), Duration{3, 2}},
+		{"multi-measure rest length", MultiMeasureRest{Bars: 4}.Length(), Duration{0, 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, test.got)
+			}
+		})
+	}
+}
+
+func TestDurationCmp(t *testing.T) {
+	var tests = []struct {
+		a, b     Duration
+		expected int
+	}{
+		{NewDuration(1, 8), NewDuration(1, 4), -1},
+		{NewDuration(2, 8), NewDuration(1, 4), 0},
+		{NewDuration(3, 16), NewDuration(1, 8), 1},
+		{Duration{}, NewDuration(0, 4), 0},
+	}
+	for _, test := range tests {
+		if got := test.a.Cmp(test.b); got != test.expected {
+			t.Errorf("%v.Cmp(%v): expected %d, got %d", test.a, test.b, test.expected, got)
+		}
+	}
+}
+
+func TestDurationString(t *testing.T) {
+	for expected, d := range map[string]Duration{
+		"3/16": {6, 32},
+		"2":    {4, 2},
+		"0":    {},
+	} {
+		if got := d.String(); got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+	}
+}
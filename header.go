@@ -0,0 +1,48 @@
+package abc
+
+// FileHeader holds the fields and directives given before the first tune
+// of a file. Each tune in the file inherits these as defaults: fields are
+// replaced by the same field in the tune, and the tune's own directives
+// and user symbols follow those of the header. Decoder.Header in package
+// parse returns the file header as read.
+//
+// The ABC standard does not allow the tempo (Q:) or parts (P:) fields in
+// a file header, as they only make sense for a single tune, so they are
+// not kept here. The key (K:) is not allowed either, but is widely used
+// to give a default key, so it is kept along with its clef.
+type FileHeader struct {
+	Area          string // deprecated
+	Book          string
+	Composer      string
+	Discography   string
+	FileURL       string
+	History       []string
+	Group         string
+	Comments      []string
+	Rhythm        string
+	Origin        string
+	Meter         Meter
+	NoteLength    NoteLength
+	Key           Key
+	Clef          Clef
+	Source        string
+	Transcription string
+	Directives    []Directive
+	UserSymbols   []UserSymbol
+}
+
+// Directive is an instruction to software processing a tune, given either
+// by an I: field or by a line starting %%, such as I:linebreak $ or
+// %%pagewidth 21cm.
+type Directive struct {
+	Name  string
+	Value string
+	Field bool // true if given by an I: field rather than a %% line
+}
+
+// UserSymbol is a character given its own meaning by a U: field, such as
+// U:T = !turn!. The characters H-W, h-w and ~ may be redefined.
+type UserSymbol struct {
+	Symbol     string
+	Decoration Decoration // Empty if the symbol was undefined with !nil!
+}
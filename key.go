@@ -0,0 +1,165 @@
+package abc
+
+// Key is the key signature of a tune, as given by the K: field.
+type Key struct {
+	Type       KeyType
+	Tonic      Pitch      // Only set for KeyStandard
+	Accidental Accidental // Sharp or flat applied to the tonic, as in F#
+	Mode       Mode
+
+	// Explicit is true if only Accidentals make up the key signature, as
+	// in K:D exp ^f _b
+	Explicit    bool
+	Accidentals []KeyAccidental // Accidentals added to the key signature
+}
+
+// KeyChange is a change of key within the body of a tune, given by a K:
+// field between lines of music or inline as [K:G]. A clef given with the
+// key applies to the voice from that point.
+type KeyChange struct {
+	Key  *Key // nil if only the clef changes, as in [K:clef=bass]
+	Clef Clef // zero if the clef is unchanged
+}
+
+// Length returns zero, since a change of key takes no time.
+func (k KeyChange) Length() Duration {
+	return Duration{}.Normalize()
+}
+
+// KeyType distinguishes standard keys from the special forms of K:.
+type KeyType int
+
+const (
+	KeyStandard               KeyType = iota
+	KeyNone                           // K:none, no key signature
+	KeyHighlandPipes                  // K:HP, bagpipe music with no key signature
+	KeyHighlandPipesSignature         // K:Hp, bagpipe music marked with F# and C#
+)
+
+// Mode is the mode of a key, such as major or Dorian.
+type Mode int
+
+const (
+	ModeMajor Mode = iota
+	ModeMinor
+	ModeIonian
+	ModeAeolian
+	ModeMixolydian
+	ModeDorian
+	ModePhrygian
+	ModeLydian
+	ModeLocrian
+)
+
+// modeFifths is the number of sharps added to (or flats, if negative) the
+// signature of a major key with the same tonic to give each mode.
+var modeFifths = map[Mode]int{
+	ModeMajor:      0,
+	ModeMinor:      -3,
+	ModeIonian:     0,
+	ModeAeolian:    -3,
+	ModeMixolydian: -1,
+	ModeDorian:     -2,
+	ModePhrygian:   -4,
+	ModeLydian:     1,
+	ModeLocrian:    -5,
+}
+
+// KeyAccidental is an accidental applied to every note of a given pitch.
+type KeyAccidental struct {
+	Pitch      Pitch
+	Accidental Accidental
+}
+
+// letterFifths is the position of each natural note on the circle of
+// fifths, relative to C.
+var letterFifths = map[Pitch]int{
+	'F': -1,
+	'C': 0,
+	'G': 1,
+	'D': 2,
+	'A': 3,
+	'E': 4,
+	'B': 5,
+}
+
+// Sharps returns the number of sharps in the key signature implied by the
+// tonic and mode, or the negated number of flats. Explicit accidentals are
+// not included.
+func (k Key) Sharps() int {
+	if k.Type != KeyStandard || k.Explicit {
+		return 0
+	}
+	fifths := letterFifths[k.Tonic] + modeFifths[k.Mode]
+	switch k.Accidental {
+	case AccidentalSharp:
+		fifths += 7
+	case AccidentalFlat:
+		fifths -= 7
+	}
+	return fifths
+}
+
+const (
+	sharpOrder = "FCGDAEB"
+	flatOrder  = "BEADGCF"
+)
+
+// Signature returns the accidental that applies to each pitch class in
+// this key. Pitches without an accidental are omitted.
+func (k Key) Signature() map[Pitch]Accidental {
+	signature := make(map[Pitch]Accidental)
+	if k.Type == KeyHighlandPipesSignature {
+		signature['F'] = AccidentalSharp
+		signature['C'] = AccidentalSharp
+	}
+
+	sharps := k.Sharps()
+	for i := 0; i < sharps && i < len(sharpOrder); i++ {
+		signature[Pitch(sharpOrder[i])] = AccidentalSharp
+	}
+	for i := 0; i < -sharps && i < len(flatOrder); i++ {
+		signature[Pitch(flatOrder[i])] = AccidentalFlat
+	}
+
+	for _, a := range k.Accidentals {
+		if a.Accidental == AccidentalNatural {
+			delete(signature, a.Pitch)
+			continue
+		}
+		signature[a.Pitch] = a.Accidental
+	}
+	return signature
+}
+
+// Clef describes how a staff is printed and played, as set by the clef
+// modifiers of the K: and V: fields.
+type Clef struct {
+	Type       ClefType
+	Line       int        // Staff line of the clef, counted from the bottom; 0 for its usual line
+	OctaveMark int        // 1 for a clef marked +8, -1 for -8
+	Middle     StaffPitch // Pitch on the middle line of the staff; zero for the clef's default
+	Transpose  int        // Semitones to transpose by when played
+	Octave     int        // Octaves to transpose notes by
+	StaffLines int        // Number of staff lines; 0 for the usual five
+}
+
+// StaffPitch is a pitch in a particular octave, with no accidental or
+// length, such as the pitch on a line of the staff.
+type StaffPitch struct {
+	Pitch  Pitch
+	Octave int // 0 for the octave from middle C, as for Note
+}
+
+// ClefType identifies the symbol used for a clef.
+type ClefType int
+
+const (
+	ClefDefault ClefType = iota // No clef given, treble is assumed
+	ClefTreble
+	ClefAlto
+	ClefTenor
+	ClefBass
+	ClefPercussion
+	ClefNone
+)
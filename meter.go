@@ -0,0 +1,111 @@
+package abc
+
+// Meter is the time signature of a tune, as given by the M: field.
+type Meter struct {
+	Type MeterType
+	// Numerator holds the terms of the upper number, which are added
+	// together for complex meters such as 2+3+2/8. Common and cut time
+	// have the numerators of 4/4 and 2/2.
+	Numerator   []int
+	Denominator int
+	// Grouped is true if the numerator is written in parentheses to show
+	// the grouping of beats, as in (2+3+2)/8.
+	Grouped bool
+}
+
+// MeterChange is a change of meter within the body of a tune, given by an
+// M: field between lines of music or inline as [M:3/4].
+type MeterChange struct {
+	Meter Meter
+}
+
+// Length returns zero, since a change of meter takes no time.
+func (m MeterChange) Length() Duration {
+	return Duration{}.Normalize()
+}
+
+// MeterType identifies how a meter is written.
+type MeterType int
+
+const (
+	MeterNumeric MeterType = iota // such as 6/8
+	MeterCommon                   // C, equivalent to 4/4
+	MeterCut                      // C|, equivalent to 2/2
+	MeterFree                     // none
+)
+
+// Free reports whether the meter is free, either because it was given as
+// M:none or because no meter was given at all.
+func (m Meter) Free() bool {
+	return m.Type == MeterFree || m.Denominator == 0
+}
+
+// BarLength returns the length of a bar as a fraction of a whole note, in
+// lowest terms. It returns zero for free meter.
+func (m Meter) BarLength() Duration {
+	if m.Free() {
+		return Duration{}.Normalize()
+	}
+	return NewDuration(m.total(), m.Denominator)
+}
+
+// BeatsPerBar returns the number of beats in each bar. Complex meters have
+// a beat for each term of the numerator, and compound meters such as 6/8
+// have a beat for every three of their notes. It returns 0 for free meter.
+func (m Meter) BeatsPerBar() int {
+	if m.Free() {
+		return 0
+	}
+	if len(m.Numerator) > 1 {
+		return len(m.Numerator)
+	}
+	if m.Compound() {
+		return m.total() / 3
+	}
+	return m.total()
+}
+
+// Compound reports whether the meter is compound, such as 6/8, 9/8 or
+// 12/8, with each beat divided into three.
+func (m Meter) Compound() bool {
+	if m.Free() || len(m.Numerator) > 1 {
+		return false
+	}
+	total := m.total()
+	return m.Denominator >= 8 && total > 3 && total%3 == 0
+}
+
+// TupletTime returns the number of notes in whose time p notes of a
+// tuplet are played, when the tuplet does not give it. For example, the
+// three notes of a triplet are played in the time of two.
+func (m Meter) TupletTime(p int) int {
+	switch p {
+	case 2, 4, 8:
+		return 3
+	case 3, 6:
+		return 2
+	}
+	if m.Compound() {
+		return 3
+	}
+	return 2
+}
+
+// DefaultNoteLength returns the unit note length of a tune with this meter
+// and no L: field: 1/16 if the meter is less than 3/4, otherwise 1/8. Free
+// meter also gives 1/8.
+func (m Meter) DefaultNoteLength() NoteLength {
+	if !m.Free() && 4*m.total() < 3*m.Denominator {
+		return NoteLength{Numerator: 1, Denominator: 16}
+	}
+	return NoteLength{Numerator: 1, Denominator: 8}
+}
+
+// total returns the sum of the terms of the numerator.
+func (m Meter) total() int {
+	total := 0
+	for _, n := range m.Numerator {
+		total += n
+	}
+	return total
+}
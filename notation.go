@@ -0,0 +1,185 @@
+package abc
+
+import "strings"
+
+// Pitch is the letter name of a note, from 'A' to 'G'.
+type Pitch rune
+
+// Accidental is a sharp, flat or natural sign applied to a note.
+type Accidental int
+
+const (
+	AccidentalNone Accidental = iota
+	AccidentalSharp
+	AccidentalFlat
+	AccidentalNatural
+	AccidentalDoubleSharp
+	AccidentalDoubleFlat
+)
+
+// Microtone is the fraction of a semitone by which a microtonal accidental
+// raises or lowers a note, such as 3/4 for ^3/4c.
+type Microtone struct {
+	Numerator   int
+	Denominator int
+}
+
+// Note is a single pitched note.
+type Note struct {
+	Pitch        Pitch
+	Octave       int // 0 for the octave from middle C (C-B), 1 for the octave above (c-b)
+	Accidental   Accidental
+	Microtone    Microtone  // Fraction of a semitone for microtonal accidentals, or zero
+	Duration     NoteLength // Multiple of the unit note length, after any broken rhythm
+	Broken       BrokenRhythm
+	ChordSymbols []ChordSymbol
+	Decorations  []Decoration
+	Tie          Tie        // Tie to the following note of the same pitch
+	Slurs        []Slur     // Slurs starting at this note
+	SlursEnded   []int      // IDs of the slurs ending at this note
+	Lyrics       []Syllable // Syllables sung to this note, one for each verse
+	BeamBreak    bool       // Separated by a space from the notation before it in the bar, ending any beam
+}
+
+func (n Note) Length() Duration {
+	return n.Duration.Normalize()
+}
+
+// Rest is a period of silence within a bar.
+type Rest struct {
+	Invisible    bool // Invisible rests (x) take up time but are not printed
+	Duration     NoteLength
+	ChordSymbols []ChordSymbol
+	Decorations  []Decoration
+	BeamBreak    bool // Separated by a space from the notation before it in the bar
+}
+
+func (r Rest) Length() Duration {
+	return r.Duration.Normalize()
+}
+
+// MultiMeasureRest is a rest lasting one or more whole bars.
+type MultiMeasureRest struct {
+	Bars int
+}
+
+// Length returns zero, since the length of a multi-measure rest depends
+// on the meter rather than the unit note length.
+func (r MultiMeasureRest) Length() Duration {
+	return Duration{}.Normalize()
+}
+
+// Chord is a set of notes played at the same time.
+type Chord struct {
+	Notes        []Note
+	Duration     NoteLength // Multiplier applied to the length of each note, after any broken rhythm
+	Broken       BrokenRhythm
+	ChordSymbols []ChordSymbol
+	Decorations  []Decoration
+	Slurs        []Slur     // Slurs starting at this chord
+	SlursEnded   []int      // IDs of the slurs ending at this chord
+	Lyrics       []Syllable // Syllables sung to this chord, one for each verse
+	BeamBreak    bool       // Separated by a space from the notation before it in the bar, ending any beam
+}
+
+// Length returns the length of the first note in the chord, multiplied
+// by the chord's duration.
+func (c Chord) Length() Duration {
+	if len(c.Notes) == 0 {
+		return Duration{}.Normalize()
+	}
+	return c.Notes[0].Duration.Mul(c.Duration)
+}
+
+// Tie joins a note to the following note of the same pitch, so that they
+// are played as one. A tie on a chord is recorded on each of its notes.
+type Tie int
+
+const (
+	TieNone   Tie = iota
+	TieSolid      // -
+	TieDotted     // .-
+)
+
+// Slur is the start of a slur over a phrase of notes, written ( before the
+// first note, or .( for a dotted slur. Slurs may be nested, and may cross
+// bar lines and lines of music.
+type Slur struct {
+	ID     int // Numbered from 1 within each tune; given in SlursEnded of the note that ends it
+	Dotted bool
+}
+
+// GraceNotes are ornamental notes played before the note or chord that
+// follows them, written in braces such as {gAg}. They take their time from
+// that note, so add nothing to the length of the bar.
+type GraceNotes struct {
+	Notes        []Note
+	Acciaccatura bool // true for a crushed grace note, written {/g}
+	BeamBreak    bool // Separated by a space from the notation before it in the bar
+}
+
+// Length returns zero, since grace notes take no time of their own.
+func (g GraceNotes) Length() Duration {
+	return Duration{}.Normalize()
+}
+
+// Tuplet is a group of notes played in the time of a different number of
+// notes of the same length, written (p:q:r. A triplet, (3, plays three
+// notes in the time of two.
+type Tuplet struct {
+	P         int        // number of notes in the group
+	Q         int        // number of notes in whose time they are played
+	R         int        // number of notes the tuplet applies to
+	Notation  []Notation // notes of the group, with their written lengths
+	BeamBreak bool       // separated by a space from the notation before it in the bar, ending any beam
+}
+
+// Length returns the total length of the notes in the group, multiplied
+// by q/p.
+func (t Tuplet) Length() Duration {
+	total := Duration{}.Normalize()
+	for _, n := range t.Notation {
+		total = total.Add(n.Length())
+	}
+	if t.P == 0 {
+		return total
+	}
+	return total.Mul(NewDuration(t.Q, t.P))
+}
+
+// BrokenRhythm is the number of > or < signs following a note or chord,
+// as in a>b or a<<b. Positive values count > signs, which lengthen the
+// note and shorten the next; negative values count < signs, which do the
+// reverse. The durations of both notes already include this change.
+type BrokenRhythm int
+
+// Factors returns the multipliers applied to the lengths of the notes
+// before and after the broken rhythm. For a>b these are 3/2 and 1/2.
+func (b BrokenRhythm) Factors() (before, after Duration) {
+	n := int(b)
+	if n < 0 {
+		n = -n
+	}
+	short := Duration{Numerator: 1, Denominator: 1 << n}
+	long := Duration{Numerator: 2<<n - 1, Denominator: 1 << n}
+	if b < 0 {
+		return short, long
+	}
+	return long, short
+}
+
+// String returns the signs of the broken rhythm, such as ">>".
+func (b BrokenRhythm) String() string {
+	if b < 0 {
+		return strings.Repeat("<", int(-b))
+	}
+	return strings.Repeat(">", int(b))
+}
+
+// Syllable is the part of a lyric sung to a single note, as given by the
+// w: field.
+type Syllable struct {
+	Text   string
+	Hyphen bool // The syllable is followed by another in the same word
+	Hold   bool // The previous syllable is held through this note
+}
@@ -0,0 +1,789 @@
+package parse
+
+import (
+	"strconv"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// barLineTypes maps bar line items to the bar line they represent.
+var barLineTypes = map[itemType]abc.BarLineType{
+	itemBarline:                abc.BarLineSingle,
+	itemDottedBarline:          abc.BarLineDotted,
+	itemThinThinDoubleBarLine:  abc.BarLineThinThin,
+	itemThinThickDoubleBarLine: abc.BarLineThinThick,
+	itemThickThinDoubleBarLine: abc.BarLineThickThin,
+	itemStartRepeat:            abc.BarLineStartRepeat,
+	itemEndRepeat:              abc.BarLineEndRepeat,
+	itemStartEndRepeats:        abc.BarLineStartEndRepeat,
+}
+
+// handleBodyItem processes an item from the body of a tune.
+func (p *parser) handleBodyItem(item *item) error {
+	if barLine, ok := barLineTypes[item.typ]; ok {
+		return p.handleBarLine(barLine)
+	}
+
+	switch item.typ {
+	case itemSpace:
+		p.beamBreak = true
+		return nil
+	case itemSharp, itemFlat, itemNatural, itemLetter:
+		if item.typ == itemLetter && item.val == "y" {
+			// Spacers only affect layout
+			return nil
+		}
+		if item.typ == itemLetter {
+			if decoration, ok := p.symbol(item.val); ok {
+				p.addDecoration(decoration)
+				return nil
+			}
+		}
+		note, err := p.parseNote(item)
+		if err != nil {
+			return err
+		}
+		return p.addNotation(item, note)
+	case itemRest, itemInvisibleRest:
+		duration, err := p.parseDuration()
+		if err != nil {
+			return err
+		}
+		return p.addNotation(item, abc.Rest{
+			Invisible: item.typ == itemInvisibleRest,
+			Duration:  duration,
+		})
+	case itemMultiMeasureRest:
+		rest := abc.MultiMeasureRest{Bars: 1}
+		if next := p.peek(); next != nil && next.typ == itemNumber {
+			p.next()
+			rest.Bars, _ = strconv.Atoi(next.val)
+		}
+		return p.addNotation(item, rest)
+	case itemOpenBracket:
+		chord, err := p.parseChord()
+		if err != nil {
+			return err
+		}
+		return p.addNotation(item, chord)
+	case itemOpenBrace:
+		grace, err := p.parseGraceNotes()
+		if err != nil {
+			return err
+		}
+		return p.addNotation(item, grace)
+	case itemDecoration:
+		if item.val == "" {
+			return p.errorf(item, "missing decoration name")
+		}
+		p.addDecoration(parseDecoration(item.val))
+		return nil
+	case itemSymbol:
+		decoration, ok := p.symbol(item.val)
+		if !ok {
+			return p.errorf(item, "undefined symbol %v", item)
+		}
+		p.addDecoration(decoration)
+		return nil
+	case itemOpenParen:
+		if next := p.peek(); next == nil || next.typ != itemNumber {
+			p.startSlur(item)
+			return nil
+		}
+		tuplet, err := p.parseTuplet()
+		if err != nil {
+			return err
+		}
+		tuplet.BeamBreak = p.takeBeamBreak()
+		p.tuplets = append(p.tuplets, tuplet)
+		return nil
+	case itemDottedSlur:
+		p.startSlur(item)
+		return nil
+	case itemCloseParen:
+		return p.endSlur(item)
+	case itemMinus, itemDottedTie:
+		// Ties directly after a note are read with the note
+		return p.errorf(item, "tie must follow a note or chord")
+	case itemVariantNumber:
+		variants, err := p.parseVariants(item)
+		if err != nil {
+			return err
+		}
+		p.setVariants(variants)
+		return nil
+	case itemChord:
+		symbol, err := parseChordSymbol(item.val)
+		if err != nil {
+			// Other text may be given in quotes, as in "N.C."
+			symbol = abc.ChordSymbol{Text: item.val}
+		}
+		if len(p.chordSymbols) == 0 {
+			p.chordStart = *item
+		}
+		p.chordSymbols = append(p.chordSymbols, symbol)
+		return nil
+	case itemAnnotationPosition, itemAnnotation:
+		// TODO: Annotations
+		return nil
+	case itemGreaterThan, itemLessThan:
+		return p.parseBrokenRhythm(item)
+	}
+	return p.errorf(item, "unexpected %s %v in tune body", item.typ, item)
+}
+
+// addNotation adds n, which starts with the given item, to the current
+// bar, first applying any broken rhythm that follows the previous note.
+func (p *parser) addNotation(item *item, n abc.Notation) error {
+	if p.takeBeamBreak() {
+		n = withBeamBreak(n)
+	}
+	if grace, ok := n.(abc.GraceNotes); ok {
+		if p.graceNotes {
+			return p.errorf(item, "grace notes must be followed by a note or chord")
+		}
+		p.graceNotes = true
+		p.appendNotation(grace)
+		return nil
+	}
+	if p.graceNotes {
+		p.graceNotes = false
+		if !isNoteOrChord(n) {
+			return p.errorf(item, "grace notes must be followed by a note or chord")
+		}
+	}
+	if len(p.chordSymbols) > 0 {
+		var ok bool
+		if n, ok = withChordSymbols(n, p.chordSymbols); !ok {
+			return p.errorf(item, "chord symbol must be followed by a note, chord or rest")
+		}
+		p.chordSymbols = nil
+	}
+	if len(p.decorations) > 0 {
+		var ok bool
+		if n, ok = withDecorations(n, p.decorations); !ok {
+			return p.errorf(item, "decoration must be followed by a note, chord, rest or bar line")
+		}
+		p.decorations = nil
+	}
+	if len(p.pendingSlurs) > 0 && isNoteOrChord(n) {
+		n = withSlurs(n, p.pendingSlurs)
+		p.pendingSlurs = nil
+	}
+	if p.broken != 0 {
+		_, after := p.broken.Factors()
+		p.broken = 0
+		var ok bool
+		if n, ok = scaleNotation(n, after); !ok {
+			return p.errorf(item, "broken rhythm must be followed by a note or chord")
+		}
+	}
+	if !p.lineMusic {
+		p.markLine()
+		p.lineMusic = true
+	}
+	p.appendNotation(n)
+	return nil
+}
+
+// appendNotation adds n to the innermost tuplet in progress, or to the
+// current bar if there is none. Each tuplet that is completed is added to
+// the tuplet or bar enclosing it.
+func (p *parser) appendNotation(n abc.Notation) {
+	for len(p.tuplets) > 0 {
+		last := len(p.tuplets) - 1
+		tuplet := &p.tuplets[last]
+		tuplet.Notation = append(tuplet.Notation, n)
+		if countNotes(tuplet.Notation) < tuplet.R {
+			return
+		}
+		n = *tuplet
+		p.tuplets = p.tuplets[:last]
+	}
+	p.currentBar.Notation = append(p.currentBar.Notation, n)
+}
+
+// takeBeamBreak reports whether a space separates the next notation from
+// the notation before it in the current tuplet or bar, and clears the
+// space.
+func (p *parser) takeBeamBreak() bool {
+	notation := p.currentBar.Notation
+	if len(p.tuplets) > 0 {
+		notation = p.tuplets[len(p.tuplets)-1].Notation
+	}
+	beamBreak := false
+	for _, n := range notation {
+		if !isChange(n) {
+			beamBreak = p.beamBreak
+			break
+		}
+	}
+	p.beamBreak = false
+	return beamBreak
+}
+
+// withBeamBreak returns n marked as separated by a space from the notation
+// before it.
+func withBeamBreak(n abc.Notation) abc.Notation {
+	switch n := n.(type) {
+	case abc.Note:
+		n.BeamBreak = true
+		return n
+	case abc.Rest:
+		n.BeamBreak = true
+		return n
+	case abc.Chord:
+		n.BeamBreak = true
+		return n
+	case abc.GraceNotes:
+		n.BeamBreak = true
+		return n
+	}
+	return n
+}
+
+// countNotes returns the number of notes, chords and rests in notation,
+// counting each nested tuplet as one. Grace notes and changes of key,
+// meter, unit note length or tempo are not counted.
+func countNotes(notation []abc.Notation) int {
+	count := 0
+	for _, n := range notation {
+		if _, ok := n.(abc.GraceNotes); !ok && !isChange(n) {
+			count++
+		}
+	}
+	return count
+}
+
+// isChange reports whether n changes the key, meter, unit note length or
+// tempo for the music that follows, rather than being part of the music
+// itself.
+func isChange(n abc.Notation) bool {
+	switch n.(type) {
+	case abc.KeyChange, abc.MeterChange, abc.NoteLengthChange, abc.TempoChange:
+		return true
+	}
+	return false
+}
+
+// isNoteOrChord reports whether n is a note or chord.
+func isNoteOrChord(n abc.Notation) bool {
+	switch n.(type) {
+	case abc.Note, abc.Chord:
+		return true
+	}
+	return false
+}
+
+// parseTuplet reads the numbers following the ( of a tuplet, such as (3,
+// (3:2 or (3:2:4. Omitted numbers take their defaults: q from the meter and
+// r from p.
+func (p *parser) parseTuplet() (abc.Tuplet, error) {
+	item, err := p.expect(itemNumber)
+	if err != nil {
+		return abc.Tuplet{}, err
+	}
+	tuplet := abc.Tuplet{}
+	tuplet.P, _ = strconv.Atoi(item.val)
+	if tuplet.P < 2 {
+		return tuplet, p.errorf(item, "invalid tuplet %v", item)
+	}
+	tuplet.Q = p.meter.TupletTime(tuplet.P)
+	tuplet.R = tuplet.P
+	for _, n := range []*int{&tuplet.Q, &tuplet.R} {
+		if next := p.peek(); next == nil || next.typ != itemColon {
+			break
+		}
+		p.next()
+		if next := p.peek(); next != nil && next.typ == itemNumber {
+			p.next()
+			*n, _ = strconv.Atoi(next.val)
+			if *n == 0 {
+				return tuplet, p.errorf(next, "invalid tuplet %v", next)
+			}
+		}
+	}
+	return tuplet, nil
+}
+
+// parseBrokenRhythm reads a run of > or < signs starting with the given
+// item, and lengthens or shortens the preceding note or chord. The next
+// note or chord is changed to match when it is added.
+func (p *parser) parseBrokenRhythm(item *item) error {
+	count := 1
+	for next := p.peek(); next != nil && next.typ == item.typ; next = p.peek() {
+		p.next()
+		count++
+	}
+	broken := abc.BrokenRhythm(count)
+	if item.typ == itemLessThan {
+		broken = -broken
+	}
+
+	if p.broken != 0 {
+		return p.errorf(item, "broken rhythm must follow a note or chord")
+	}
+	// The note may end a tuplet, as in (3ABc>d
+	before, _ := broken.Factors()
+	ok := p.updateLast(func(n abc.Notation) (abc.Notation, bool) {
+		n, ok := scaleNotation(n, before)
+		switch n := n.(type) {
+		case abc.Note:
+			n.Broken = broken
+			return n, ok
+		case abc.Chord:
+			n.Broken = broken
+			return n, ok
+		}
+		return n, ok
+	})
+	if !ok {
+		return p.errorf(item, "broken rhythm must follow a note or chord")
+	}
+	p.broken = broken
+	return nil
+}
+
+// scaleNotation multiplies the duration of a note or chord by factor. It
+// returns false for any other notation.
+func scaleNotation(n abc.Notation, factor abc.Duration) (abc.Notation, bool) {
+	switch n := n.(type) {
+	case abc.Note:
+		n.Duration = n.Duration.Mul(factor)
+		return n, true
+	case abc.Chord:
+		n.Duration = n.Duration.Mul(factor)
+		return n, true
+	}
+	return n, false
+}
+
+// handleBarLine completes the current bar, if it contains any notation.
+// Otherwise, the bar line is taken to be the start of the current bar.
+func (p *parser) handleBarLine(typ abc.BarLineType) error {
+	if p.broken != 0 {
+		return p.errorf(p.last, "broken rhythm must be followed by a note or chord")
+	}
+	if len(p.tuplets) > 0 {
+		return p.errorf(p.last, "tuplet must end before the bar line")
+	}
+	if p.graceNotes {
+		return p.errorf(p.last, "grace notes must be followed by a note or chord")
+	}
+	barLine := abc.BarLine{Type: typ, ChordSymbols: p.chordSymbols, Decorations: p.decorations}
+	p.chordSymbols = nil
+	p.decorations = nil
+	p.beamBreak = false
+
+	if len(p.currentBar.Notation) == 0 {
+		// Bar lines with no music between them, such as the :| ending one
+		// line and the |: starting the next, make up a single bar line
+		barLine = joinBarLines(p.currentBar.Left, barLine)
+		p.currentBar.Left = barLine
+		if bars := *p.bars(); len(bars) > 0 {
+			bars[len(bars)-1].Right = barLine
+		}
+		return nil
+	}
+	p.currentBar.Right = barLine
+	p.flushBar()
+	p.currentBar.Left = barLine
+	return nil
+}
+
+// joinBarLines combines two bar lines with no music between them. An end
+// repeat followed by a start repeat becomes ::, and a single bar line adds
+// nothing to the one before it. Otherwise the second bar line is taken.
+func joinBarLines(first, second abc.BarLine) abc.BarLine {
+	joined := second
+	switch {
+	case first.Type == abc.BarLineEndRepeat && second.Type == abc.BarLineStartRepeat:
+		joined.Type = abc.BarLineStartEndRepeat
+	case first.Type != abc.BarLineNone && second.Type == abc.BarLineSingle:
+		joined.Type = first.Type
+	}
+	if len(joined.Variants) == 0 {
+		joined.Variants = first.Variants
+	}
+	joined.ChordSymbols = append(first.ChordSymbols, second.ChordSymbols...)
+	joined.Decorations = append(first.Decorations, second.Decorations...)
+	return joined
+}
+
+// parseVariants reads the list of variant endings following a bar line or
+// a [, such as |1, [1,3 or :|1-3.
+func (p *parser) parseVariants(first *item) ([]int, error) {
+	var variants []int
+	from, err := p.parseVariant(first)
+	if err != nil {
+		return nil, err
+	}
+	variants = append(variants, from)
+	for next := p.peek(); next != nil; next = p.peek() {
+		switch next.typ {
+		case itemVariantComma:
+			p.next()
+			item, err := p.expect(itemVariantNumber)
+			if err != nil {
+				return nil, err
+			}
+			if from, err = p.parseVariant(item); err != nil {
+				return nil, err
+			}
+			variants = append(variants, from)
+		case itemVariantRange:
+			p.next()
+			item, err := p.expect(itemVariantNumber)
+			if err != nil {
+				return nil, err
+			}
+			to, err := p.parseVariant(item)
+			if err != nil {
+				return nil, err
+			}
+			if to < from {
+				return nil, p.errorf(item, "invalid variant range %d-%d", from, to)
+			}
+			for v := from + 1; v <= to; v++ {
+				variants = append(variants, v)
+			}
+		default:
+			return variants, nil
+		}
+	}
+	return variants, nil
+}
+
+// parseVariant reads a single variant ending number.
+func (p *parser) parseVariant(item *item) (int, error) {
+	variant, err := strconv.Atoi(item.val)
+	if err != nil {
+		return 0, p.errorf(item, "invalid variant ending %s", item.val)
+	}
+	return variant, nil
+}
+
+// setVariants marks the current bar as the start of the given variant
+// endings. If the bar has just begun, the bar line it shares with the
+// previous bar is updated too.
+func (p *parser) setVariants(variants []int) {
+	p.currentBar.Left.Variants = variants
+	bars := *p.bars()
+	if len(p.currentBar.Notation) == 0 && len(bars) > 0 {
+		bars[len(bars)-1].Right.Variants = variants
+	}
+}
+
+// parseNote reads a note, starting with either its accidental or letter.
+func (p *parser) parseNote(item *item) (abc.Note, error) {
+	note := abc.Note{}
+	if item.typ != itemLetter {
+		var err error
+		if note.Accidental, note.Microtone, err = p.parseAccidental(item); err != nil {
+			return note, err
+		}
+		if item, err = p.expect(itemLetter); err != nil {
+			return note, err
+		}
+	}
+
+	letter := item.val[0]
+	switch {
+	case letter >= 'A' && letter <= 'G':
+		note.Pitch = abc.Pitch(letter)
+	case letter >= 'a' && letter <= 'g':
+		note.Pitch = abc.Pitch(letter - 'a' + 'A')
+		note.Octave = 1
+	default:
+		return note, p.errorf(item, "unexpected letter %v in tune body", item)
+	}
+
+	// Octave marks, as in c' or C,
+	for next := p.peek(); next != nil; next = p.peek() {
+		if next.typ == itemOctaveUp {
+			note.Octave++
+		} else if next.typ == itemOctaveDown {
+			note.Octave--
+		} else {
+			break
+		}
+		p.next()
+	}
+
+	var err error
+	if note.Duration, err = p.parseDuration(); err != nil {
+		return note, err
+	}
+	note.Tie = p.parseTie()
+	return note, nil
+}
+
+// parseTie reads an optional tie following a note.
+func (p *parser) parseTie() abc.Tie {
+	next := p.peek()
+	if next == nil {
+		return abc.TieNone
+	}
+	switch next.typ {
+	case itemMinus:
+		p.next()
+		return abc.TieSolid
+	case itemDottedTie:
+		p.next()
+		return abc.TieDotted
+	}
+	return abc.TieNone
+}
+
+// parseAccidental reads the accidental of a note starting with the given
+// item, including doubled accidentals such as ^^ and microtonal
+// accidentals such as ^3/4 or _/.
+func (p *parser) parseAccidental(item *item) (abc.Accidental, abc.Microtone, error) {
+	switch item.typ {
+	case itemNatural:
+		return abc.AccidentalNatural, abc.Microtone{}, nil
+	case itemSharp, itemFlat:
+	default:
+		return abc.AccidentalNone, abc.Microtone{}, p.unexpected(item, itemSharp, itemFlat, itemNatural)
+	}
+
+	if next := p.peek(); next != nil && next.typ == item.typ {
+		p.next()
+		if item.typ == itemSharp {
+			return abc.AccidentalDoubleSharp, abc.Microtone{}, nil
+		}
+		return abc.AccidentalDoubleFlat, abc.Microtone{}, nil
+	}
+
+	accidental := abc.AccidentalSharp
+	if item.typ == itemFlat {
+		accidental = abc.AccidentalFlat
+	}
+	if next := p.peek(); next == nil || (next.typ != itemNumber && next.typ != itemDivide) {
+		return accidental, abc.Microtone{}, nil
+	}
+	fraction, err := p.parseFraction()
+	if err != nil {
+		return accidental, abc.Microtone{}, err
+	}
+	if fraction.Denominator == 1 {
+		return accidental, abc.Microtone{}, p.errorf(item, "invalid microtonal accidental")
+	}
+	return accidental, abc.Microtone{Numerator: fraction.Numerator, Denominator: fraction.Denominator}, nil
+}
+
+// parseChord reads the notes of a chord up to and including the closing
+// bracket, followed by the length of the chord.
+func (p *parser) parseChord() (abc.Chord, error) {
+	chord := abc.Chord{}
+	for {
+		item := p.next()
+		if item == nil {
+			return chord, p.errorf(item, "unterminated chord")
+		}
+		switch item.typ {
+		case itemCloseBracket:
+			var err error
+			if chord.Duration, err = p.parseDuration(); err != nil {
+				return chord, err
+			}
+			// A tie after the chord ties each of its notes
+			if tie := p.parseTie(); tie != abc.TieNone {
+				for i := range chord.Notes {
+					chord.Notes[i].Tie = tie
+				}
+			}
+			return chord, nil
+		case itemSharp, itemFlat, itemNatural, itemLetter:
+			note, err := p.parseNote(item)
+			if err != nil {
+				return chord, err
+			}
+			chord.Notes = append(chord.Notes, note)
+		default:
+			return chord, p.errorf(item, "unexpected %s %v in chord", item.typ, item)
+		}
+	}
+}
+
+// parseGraceNotes reads the notes of a group of grace notes up to and
+// including the closing brace. A slash after the opening brace marks an
+// acciaccatura.
+func (p *parser) parseGraceNotes() (abc.GraceNotes, error) {
+	grace := abc.GraceNotes{}
+	if next := p.peek(); next != nil && next.typ == itemDivide {
+		p.next()
+		grace.Acciaccatura = true
+	}
+	for {
+		item := p.next()
+		if item == nil {
+			return grace, p.errorf(item, "unterminated grace notes")
+		}
+		switch item.typ {
+		case itemCloseBrace:
+			if len(grace.Notes) == 0 {
+				return grace, p.errorf(item, "grace notes must contain a note")
+			}
+			return grace, nil
+		case itemSharp, itemFlat, itemNatural, itemLetter:
+			note, err := p.parseNote(item)
+			if err != nil {
+				return grace, err
+			}
+			grace.Notes = append(grace.Notes, note)
+		default:
+			return grace, p.errorf(item, "unexpected %s %v in grace notes", item.typ, item)
+		}
+	}
+}
+
+// parseDuration reads an optional length multiplier, such as 2, 3/2, /4
+// or the shorthand / for /2 and // for /4. When no length is given, the
+// unit note length is assumed.
+func (p *parser) parseDuration() (abc.NoteLength, error) {
+	return p.parseFraction()
+}
+
+// parseFraction reads an optional fraction made up of a numerator,
+// defaulting to 1, and a denominator following a slash. Each slash
+// without a denominator halves the fraction.
+func (p *parser) parseFraction() (abc.NoteLength, error) {
+	fraction := abc.NoteLength{
+		Numerator:   1,
+		Denominator: 1,
+	}
+	if next := p.peek(); next != nil && next.typ == itemNumber {
+		p.next()
+		fraction.Numerator, _ = strconv.Atoi(next.val)
+	}
+	for next := p.peek(); next != nil && next.typ == itemDivide; next = p.peek() {
+		p.next()
+		if next := p.peek(); next != nil && next.typ == itemNumber {
+			p.next()
+			divisor, _ := strconv.Atoi(next.val)
+			if divisor == 0 {
+				return fraction, p.errorf(next, "invalid note length divisor %v", next)
+			}
+			fraction.Denominator *= divisor
+			break
+		}
+		fraction.Denominator *= 2
+	}
+	return fraction, nil
+}
+
+// startSlur begins a slur at the given ( or .( item. The slur starts at
+// the next note or chord.
+func (p *parser) startSlur(item *item) {
+	p.slurID++
+	slur := abc.Slur{ID: p.slurID, Dotted: item.typ == itemDottedSlur}
+	p.pendingSlurs = append(p.pendingSlurs, slur)
+	p.openSlurs = append(p.openSlurs, openSlur{Slur: slur, start: *item})
+}
+
+// endSlur ends the most recently started slur that is still open at the
+// last note or chord.
+func (p *parser) endSlur(item *item) error {
+	if len(p.openSlurs) == 0 {
+		return p.errorf(item, "slur ended without being started")
+	}
+	last := len(p.openSlurs) - 1
+	slur := p.openSlurs[last]
+	for _, pending := range p.pendingSlurs {
+		if pending.ID == slur.ID {
+			return p.errorf(item, "slur must contain a note or chord")
+		}
+	}
+	if !p.updateLast(func(n abc.Notation) (abc.Notation, bool) {
+		switch n := n.(type) {
+		case abc.Note:
+			n.SlursEnded = append(n.SlursEnded, slur.ID)
+			return n, true
+		case abc.Chord:
+			n.SlursEnded = append(n.SlursEnded, slur.ID)
+			return n, true
+		}
+		return n, false
+	}) {
+		return p.errorf(item, "slur must end after a note or chord")
+	}
+	p.openSlurs = p.openSlurs[:last]
+	return nil
+}
+
+// firstOpenSlur returns the earliest started slur that is still open in
+// any voice, or nil if there is none.
+func (p *parser) firstOpenSlur() *openSlur {
+	var first *openSlur
+	check := func(slurs []openSlur) {
+		if len(slurs) > 0 && (first == nil || slurs[0].start.pos < first.start.pos) {
+			first = &slurs[0]
+		}
+	}
+	check(p.openSlurs)
+	for _, state := range p.pendingVoices {
+		check(state.openSlurs)
+	}
+	return first
+}
+
+// firstChordSymbol returns the earliest chord symbol in any voice that
+// still awaits the notation it applies to, or nil if there is none.
+func (p *parser) firstChordSymbol() *item {
+	var first *item
+	check := func(symbols []abc.ChordSymbol, start *item) {
+		if len(symbols) > 0 && (first == nil || start.pos < first.pos) {
+			first = start
+		}
+	}
+	check(p.chordSymbols, &p.chordStart)
+	for _, state := range p.pendingVoices {
+		state := state
+		check(state.chordSymbols, &state.chordStart)
+	}
+	return first
+}
+
+// openSlur is a slur that has started but not yet ended.
+type openSlur struct {
+	abc.Slur
+	start item // the ( that started the slur, for error reports
+}
+
+// withSlurs returns the note or chord n with the given slurs starting at
+// it.
+func withSlurs(n abc.Notation, slurs []abc.Slur) abc.Notation {
+	switch n := n.(type) {
+	case abc.Note:
+		n.Slurs = append(n.Slurs, slurs...)
+		return n
+	case abc.Chord:
+		n.Slurs = append(n.Slurs, slurs...)
+		return n
+	}
+	return n
+}
+
+// updateLast replaces the last notation added to the current bar with the
+// result of update, looking inside any tuplet it ends with. It returns
+// false if there is no notation, or if update does.
+func (p *parser) updateLast(update func(abc.Notation) (abc.Notation, bool)) bool {
+	notation := p.currentBar.Notation
+	if len(p.tuplets) > 0 {
+		notation = p.tuplets[len(p.tuplets)-1].Notation
+	}
+	for len(notation) > 0 {
+		last := len(notation) - 1
+		if tuplet, ok := notation[last].(abc.Tuplet); ok {
+			notation = tuplet.Notation
+			continue
+		}
+		n, ok := update(notation[last])
+		if ok {
+			notation[last] = n
+		}
+		return ok
+	}
+	return false
+}
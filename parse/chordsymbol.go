@@ -0,0 +1,173 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// chordQualities maps the ways of writing the quality of a chord to the
+// quality they give, longest first so that the longest match is taken. A
+// following 7, 9, 11 or 13 may turn the quality into a seventh chord.
+var chordQualities = []struct {
+	text    string
+	quality abc.ChordQuality
+}{
+	{"m7b5", abc.ChordHalfDiminished},
+	{"sus2", abc.ChordSuspendedSecond},
+	{"sus4", abc.ChordSuspendedFourth},
+	{"maj", abc.ChordMajorSeventh},
+	{"min", abc.ChordMinor},
+	{"dim", abc.ChordDiminished},
+	{"aug", abc.ChordAugmented},
+	{"sus", abc.ChordSuspendedFourth},
+	{"M", abc.ChordMajorSeventh},
+	{"m", abc.ChordMinor},
+	{"-", abc.ChordMinor},
+	{"o", abc.ChordDiminished},
+	{"+", abc.ChordAugmented},
+}
+
+// chordSevenths maps the qualities that take a seventh from a following
+// 7, 9, 11 or 13 to the seventh chords they become.
+var chordSevenths = map[abc.ChordQuality]abc.ChordQuality{
+	abc.ChordMajor:        abc.ChordDominant,
+	abc.ChordMinor:        abc.ChordMinorSeventh,
+	abc.ChordMajorSeventh: abc.ChordMajorSeventh,
+	abc.ChordDiminished:   abc.ChordDiminishedSeventh,
+}
+
+// parseChordSymbol parses the text of a chord symbol, such as Am7/G or
+// F#m7b5.
+func parseChordSymbol(text string) (abc.ChordSymbol, error) {
+	symbol := abc.ChordSymbol{}
+	rest := strings.TrimSpace(text)
+	var ok bool
+	if symbol.Root, symbol.Accidental, rest, ok = parseChordRoot(rest); !ok {
+		return symbol, fmt.Errorf("invalid chord symbol %q", text)
+	}
+
+	// An alternate bass note follows the last slash, unlike the slash of
+	// a 6/9 chord
+	if slash := strings.LastIndex(rest, "/"); slash >= 0 {
+		var bassRest string
+		symbol.Bass, symbol.BassAccidental, bassRest, ok = parseChordRoot(rest[slash+1:])
+		if ok {
+			if bassRest != "" {
+				return symbol, fmt.Errorf("invalid bass note in chord symbol %q", text)
+			}
+			rest = rest[:slash]
+		}
+	}
+
+	if rest == "5" {
+		symbol.Quality = abc.ChordPower
+		return symbol, nil
+	}
+	for _, q := range chordQualities {
+		if strings.HasPrefix(rest, q.text) {
+			symbol.Quality = q.quality
+			rest = rest[len(q.text):]
+			break
+		}
+	}
+
+	// A seventh chord, or one extended from it such as C9
+	number := leadingDigits(rest)
+	switch number {
+	case "7", "9", "11", "13":
+		seventh, ok := chordSevenths[symbol.Quality]
+		if !ok {
+			break
+		}
+		symbol.Quality = seventh
+		if number != "7" {
+			symbol.Extensions = append(symbol.Extensions, number)
+		}
+		rest = rest[len(number):]
+	default:
+		if symbol.Quality == abc.ChordMajorSeventh {
+			// Such as Cmaj, which is just a major chord
+			symbol.Quality = abc.ChordMajor
+		}
+	}
+
+	for _, extension := range strings.FieldsFunc(rest, isExtensionSeparator) {
+		for extension != "" {
+			length := extensionLength(extension)
+			if length == 0 {
+				return symbol, fmt.Errorf("invalid chord symbol %q", text)
+			}
+			symbol.Extensions = append(symbol.Extensions, extension[:length])
+			extension = extension[length:]
+		}
+	}
+	return symbol, nil
+}
+
+// withChordSymbols returns n with the given chord symbols added. It
+// returns false if n cannot have chord symbols.
+func withChordSymbols(n abc.Notation, symbols []abc.ChordSymbol) (abc.Notation, bool) {
+	switch n := n.(type) {
+	case abc.Note:
+		n.ChordSymbols = append(n.ChordSymbols, symbols...)
+		return n, true
+	case abc.Chord:
+		n.ChordSymbols = append(n.ChordSymbols, symbols...)
+		return n, true
+	case abc.Rest:
+		n.ChordSymbols = append(n.ChordSymbols, symbols...)
+		return n, true
+	}
+	return n, false
+}
+
+// parseChordRoot reads the note name at the start of a chord symbol or
+// bass note, along with its accidental, returning the remaining text.
+func parseChordRoot(text string) (abc.Pitch, abc.Accidental, string, bool) {
+	if text == "" || text[0] < 'A' || text[0] > 'G' {
+		return 0, abc.AccidentalNone, text, false
+	}
+	pitch := abc.Pitch(text[0])
+	text = text[1:]
+	switch {
+	case strings.HasPrefix(text, "#"):
+		return pitch, abc.AccidentalSharp, text[1:], true
+	case strings.HasPrefix(text, "b"):
+		return pitch, abc.AccidentalFlat, text[1:], true
+	}
+	return pitch, abc.AccidentalNone, text, true
+}
+
+// extensionLength returns the length of the extension at the start of
+// text, such as 9, b9, #11, add9 or sus4, or 0 if there is none.
+func extensionLength(text string) int {
+	prefix := 0
+	for _, p := range []string{"add", "sus", "b", "#"} {
+		if strings.HasPrefix(text, p) {
+			prefix = len(p)
+			break
+		}
+	}
+	digits := leadingDigits(text[prefix:])
+	if digits == "" {
+		return 0
+	}
+	return prefix + len(digits)
+}
+
+// isExtensionSeparator reports whether r separates the extensions of a
+// chord symbol, as in C7(b9,#11) or C6/9.
+func isExtensionSeparator(r rune) bool {
+	return r == '(' || r == ')' || r == ',' || r == ' ' || r == '/'
+}
+
+// leadingDigits returns the digits at the start of text.
+func leadingDigits(text string) string {
+	end := 0
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+	return text[:end]
+}
@@ -0,0 +1,93 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+func TestParseChordSymbol(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected abc.ChordSymbol
+	}{
+		{
+			input:    "G",
+			expected: abc.ChordSymbol{Root: 'G'},
+		},
+		{
+			input:    "Am7/G",
+			expected: abc.ChordSymbol{Root: 'A', Quality: abc.ChordMinorSeventh, Bass: 'G'},
+		},
+		{
+			input:    "F#m7b5",
+			expected: abc.ChordSymbol{Root: 'F', Accidental: abc.AccidentalSharp, Quality: abc.ChordHalfDiminished},
+		},
+		{
+			input:    "Bbmaj7",
+			expected: abc.ChordSymbol{Root: 'B', Accidental: abc.AccidentalFlat, Quality: abc.ChordMajorSeventh},
+		},
+		{
+			input:    "C9",
+			expected: abc.ChordSymbol{Root: 'C', Quality: abc.ChordDominant, Extensions: []string{"9"}},
+		},
+		{
+			input:    "Dm",
+			expected: abc.ChordSymbol{Root: 'D', Quality: abc.ChordMinor},
+		},
+		{
+			input:    "Edim7",
+			expected: abc.ChordSymbol{Root: 'E', Quality: abc.ChordDiminishedSeventh},
+		},
+		{
+			input:    "Caug",
+			expected: abc.ChordSymbol{Root: 'C', Quality: abc.ChordAugmented},
+		},
+		{
+			input:    "Asus4",
+			expected: abc.ChordSymbol{Root: 'A', Quality: abc.ChordSuspendedFourth},
+		},
+		{
+			input:    "G7sus4",
+			expected: abc.ChordSymbol{Root: 'G', Quality: abc.ChordDominant, Extensions: []string{"sus4"}},
+		},
+		{
+			input:    "C6/9",
+			expected: abc.ChordSymbol{Root: 'C', Extensions: []string{"6", "9"}},
+		},
+		{
+			input:    "G7(b9,#11)",
+			expected: abc.ChordSymbol{Root: 'G', Quality: abc.ChordDominant, Extensions: []string{"b9", "#11"}},
+		},
+		{
+			input:    "Cadd9/E",
+			expected: abc.ChordSymbol{Root: 'C', Extensions: []string{"add9"}, Bass: 'E'},
+		},
+		{
+			input:    "D5/F#",
+			expected: abc.ChordSymbol{Root: 'D', Quality: abc.ChordPower, Bass: 'F', BassAccidental: abc.AccidentalSharp},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := parseChordSymbol(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(test.expected, got) {
+				t.Errorf("chord symbols did not match: %v", cmp.Diff(test.expected, got))
+			}
+		})
+	}
+}
+
+func TestParseChordSymbolErrors(t *testing.T) {
+	for _, input := range []string{"", "H", "am", "Cxyz", "C/Gm"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseChordSymbol(input); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
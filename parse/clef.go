@@ -0,0 +1,120 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// clefTypes maps the name of each clef to its type.
+var clefTypes = map[string]abc.ClefType{
+	"treble": abc.ClefTreble,
+	"alto":   abc.ClefAlto,
+	"tenor":  abc.ClefTenor,
+	"bass":   abc.ClefBass,
+	"perc":   abc.ClefPercussion,
+	"none":   abc.ClefNone,
+}
+
+// isClefField reports whether field is part of the clef description of a
+// K: or V: field, either as a modifier such as middle=d or a bare clef name.
+func isClefField(field string) bool {
+	if name, _, ok := strings.Cut(field, "="); ok {
+		switch name {
+		case "clef", "middle", "m", "transpose", "t", "octave", "stafflines":
+			return true
+		}
+		return false
+	}
+	_, err := parseClefName(field)
+	return err == nil
+}
+
+// parseClefField applies a single clef modifier or clef name to clef.
+func parseClefField(clef *abc.Clef, field string) error {
+	name, value, ok := strings.Cut(field, "=")
+	if !ok {
+		name, value = "clef", field
+	}
+
+	var err error
+	switch name {
+	case "clef":
+		var named abc.Clef
+		named, err = parseClefName(value)
+		clef.Type, clef.Line, clef.OctaveMark = named.Type, named.Line, named.OctaveMark
+	case "middle", "m":
+		clef.Middle, err = parsePitch(value)
+	case "transpose", "t":
+		clef.Transpose, err = strconv.Atoi(value)
+	case "octave":
+		clef.Octave, err = strconv.Atoi(value)
+	case "stafflines":
+		clef.StaffLines, err = strconv.Atoi(value)
+	default:
+		return fmt.Errorf("unknown clef modifier %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", name, value)
+	}
+	return nil
+}
+
+// parseClefName parses a clef name with an optional staff line and octave
+// mark, such as bass, alto1 or treble-8.
+func parseClefName(value string) (abc.Clef, error) {
+	clef := abc.Clef{}
+	for name, typ := range clefTypes {
+		if !strings.HasPrefix(value, name) {
+			continue
+		}
+		clef.Type = typ
+		rest := value[len(name):]
+		if len(rest) > 0 && rest[0] >= '1' && rest[0] <= '5' {
+			clef.Line = int(rest[0] - '0')
+			rest = rest[1:]
+		}
+		switch rest {
+		case "":
+		case "+8":
+			clef.OctaveMark = 1
+		case "-8":
+			clef.OctaveMark = -1
+		default:
+			return clef, fmt.Errorf("invalid clef %q", value)
+		}
+		return clef, nil
+	}
+	return clef, fmt.Errorf("unknown clef %q", value)
+}
+
+// parsePitch parses a single pitch with octave marks, such as B, or c'.
+func parsePitch(value string) (abc.StaffPitch, error) {
+	pitch := abc.StaffPitch{}
+	if value == "" {
+		return pitch, fmt.Errorf("missing pitch")
+	}
+	letter := value[0]
+	switch {
+	case letter >= 'A' && letter <= 'G':
+		pitch.Pitch = abc.Pitch(letter)
+	case letter >= 'a' && letter <= 'g':
+		pitch.Pitch = abc.Pitch(letter - 'a' + 'A')
+		pitch.Octave = 1
+	default:
+		return pitch, fmt.Errorf("invalid pitch %q", value)
+	}
+	for _, mark := range value[1:] {
+		switch mark {
+		case '\'':
+			pitch.Octave++
+		case ',':
+			pitch.Octave--
+		default:
+			return pitch, fmt.Errorf("invalid pitch %q", value)
+		}
+	}
+	return pitch, nil
+}
@@ -0,0 +1,99 @@
+package parse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// A Decoder reads and parses tunes from an input stream one at a time,
+// so that large tune books need not be held in memory.
+type Decoder struct {
+	r      *bufio.Reader
+	parser *parser
+	err    error // error to return once all parsed tunes are decoded
+}
+
+// NewDecoder returns a new decoder that reads from in.
+//
+// The decoder introduces its own buffering and may read data from in
+// beyond the tunes requested.
+func NewDecoder(in io.Reader) *Decoder {
+	return &Decoder{
+		r:      bufio.NewReader(in),
+		parser: &parser{name: readerName(in), lineEmpty: true},
+	}
+}
+
+// Decode reads the next tune from the input and stores it in the value
+// pointed to by tune. At the end of the input, Decode returns io.EOF.
+// Once an error has been returned, every later call returns it again.
+func (d *Decoder) Decode(tune *abc.Tune) error {
+	return d.DecodeContext(context.Background(), tune)
+}
+
+// DecodeContext is like Decode, but stops parsing and returns the
+// context's error if ctx is done before the next tune is parsed. Reads
+// from the input are not interrupted by ctx.
+func (d *Decoder) DecodeContext(ctx context.Context, tune *abc.Tune) error {
+	p := d.parser
+	p.ctx = ctx
+	for len(p.tunes) == 0 {
+		if d.err != nil {
+			return d.err
+		}
+		d.err = d.parseBlock()
+	}
+	*tune = p.tunes[0]
+	p.tunes = p.tunes[1:]
+	return nil
+}
+
+// Header returns the file header, which is complete once the first tune
+// has been decoded.
+func (d *Decoder) Header() abc.FileHeader {
+	return d.parser.header
+}
+
+// parseBlock reads and parses input up to and including the next empty
+// line. As an empty line always ends a tune, each block can be lexed
+// separately while the parser carries over from one block to the next.
+func (d *Decoder) parseBlock() error {
+	block, readErr := d.readBlock()
+	if readErr != nil && readErr != io.EOF {
+		return readErr
+	}
+
+	p := d.parser
+	p.lexer = lex(p.ctx, p.name, block)
+	defer p.lexer.stop()
+	err := p.parseItems()
+	if err == nil && readErr == io.EOF {
+		err = p.endTune()
+	}
+	if err != nil {
+		return err
+	}
+	p.line += strings.Count(block, "\n")
+	p.offset += len(block)
+	return readErr
+}
+
+// readBlock reads lines from the input until an empty line or the end of
+// the input is reached.
+func (d *Decoder) readBlock() (string, error) {
+	var block strings.Builder
+	for {
+		line, err := d.r.ReadString('\n')
+		block.WriteString(line)
+		if err != nil {
+			return block.String(), err
+		}
+		if line == "\n" || line == "\r\n" {
+			return block.String(), nil
+		}
+	}
+}
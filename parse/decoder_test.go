@@ -0,0 +1,112 @@
+package parse
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+const decoderInput = `%abc-2.1
+Free text before the first tune
+
+X:1
+T:First
+K:G
+ABc|
+
+X:2
+T:Second
+K:D
+def|
+
+X:3
+T:Third
+K:C
+g|
+`
+
+func TestDecoder(t *testing.T) {
+	expected, err := Read(strings.NewReader(decoderInput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []abc.Tune
+	d := NewDecoder(strings.NewReader(decoderInput))
+	for true {
+		var tune abc.Tune
+		err := d.Decode(&tune)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, tune)
+	}
+	if len(got) != 3 {
+		t.Errorf("expected 3 tunes, got %d", len(got))
+	}
+	if !cmp.Equal(expected, got) {
+		t.Errorf("tunes did not match: %v", cmp.Diff(expected, got))
+	}
+
+	var tune abc.Tune
+	if err := d.Decode(&tune); err != io.EOF {
+		t.Errorf("expected io.EOF after the last tune, got %v", err)
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	input := "X:1\nK:G\nA|\n\nX:2\nK:G\nB$|\n"
+	d := NewDecoder(strings.NewReader(input))
+
+	var tune abc.Tune
+	if err := d.Decode(&tune); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tune.Sequence != 1 {
+		t.Errorf("expected tune 1, got %d", tune.Sequence)
+	}
+
+	err := d.Decode(&tune)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	expected := ParseError{Line: 7, Column: 2, Offset: 21, Msg: "unknown character: $"}
+	if !cmp.Equal(expected, *parseErr) {
+		t.Errorf("errors did not match: %v", cmp.Diff(expected, *parseErr))
+	}
+	if again := d.Decode(&tune); again != err {
+		t.Errorf("expected the same error again, got %v", again)
+	}
+}
+
+func TestDecoderStreams(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	go func() {
+		io.WriteString(w, "X:1\nT:First\nK:G\nA|\n\n")
+		// The rest of the input is never written
+	}()
+
+	done := make(chan error)
+	go func() {
+		var tune abc.Tune
+		done <- NewDecoder(r).Decode(&tune)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("timed out waiting for the first tune")
+	}
+}
@@ -0,0 +1,126 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// decorationAliases maps the alternative names of decorations given by the
+// ABC standard to the names used by abc.Decoration. The nil decoration
+// stands for no decoration at all.
+var decorationAliases = map[string]abc.Decoration{
+	"nil":          "",
+	"mordent":      abc.DecorationLowerMordent,
+	"pralltriller": abc.DecorationUpperMordent,
+	">":            abc.DecorationAccent,
+	"emphasis":     abc.DecorationAccent,
+	"+":            abc.DecorationPlus,
+	"<(":           abc.DecorationCrescendoStart,
+	"<)":           abc.DecorationCrescendoEnd,
+	">(":           abc.DecorationDiminuendoStart,
+	">)":           abc.DecorationDiminuendoEnd,
+}
+
+// defaultSymbols maps the single characters that stand for decorations,
+// such as ~ for a roll, to the decorations they stand for.
+var defaultSymbols = map[string]abc.Decoration{
+	"~": abc.DecorationRoll,
+	".": abc.DecorationStaccato,
+	"H": abc.DecorationFermata,
+	"L": abc.DecorationAccent,
+	"M": abc.DecorationLowerMordent,
+	"O": abc.DecorationCoda,
+	"P": abc.DecorationUpperMordent,
+	"S": abc.DecorationSegno,
+	"T": abc.DecorationTrill,
+	"u": abc.DecorationUpBow,
+	"v": abc.DecorationDownBow,
+}
+
+// parseDecoration returns the decoration with the given name, as written
+// between exclamation marks.
+func parseDecoration(name string) abc.Decoration {
+	if decoration, ok := decorationAliases[name]; ok {
+		return decoration
+	}
+	return abc.Decoration(name)
+}
+
+// addDecoration adds a decoration to those awaiting the next note, chord,
+// rest or bar line.
+func (p *parser) addDecoration(decoration abc.Decoration) {
+	if decoration == "" {
+		return
+	}
+	p.decorations = append(p.decorations, decoration)
+}
+
+// symbol returns the decoration that a character stands for in the
+// current tune, either as given by a U: field or by default.
+func (p *parser) symbol(s string) (abc.Decoration, bool) {
+	symbols := p.currentTune.UserSymbols
+	for i := len(symbols) - 1; i >= 0; i-- {
+		if symbols[i].Symbol == s {
+			return symbols[i].Decoration, true
+		}
+	}
+	decoration, ok := defaultSymbols[s]
+	return decoration, ok
+}
+
+// addUserSymbol handles a U: field, which redefines a symbol for the rest
+// of the tune, or for every tune when given in the file header.
+func (p *parser) addUserSymbol() error {
+	value, err := p.expectString()
+	if err != nil {
+		return err
+	}
+	symbol, err := parseUserSymbol(value)
+	if err != nil {
+		return err
+	}
+	p.currentTune.UserSymbols = append(p.currentTune.UserSymbols, symbol)
+	return nil
+}
+
+// parseUserSymbol parses the value of a U: field, such as T = !turn!.
+func parseUserSymbol(value string) (abc.UserSymbol, error) {
+	symbol, definition, ok := strings.Cut(value, "=")
+	if !ok {
+		return abc.UserSymbol{}, fmt.Errorf("missing = in symbol definition %q", value)
+	}
+	symbol = strings.TrimSpace(symbol)
+	if len(symbol) != 1 || !isUserSymbol(symbol[0]) {
+		return abc.UserSymbol{}, fmt.Errorf("cannot redefine symbol %q", symbol)
+	}
+	definition = strings.TrimSpace(definition)
+	last := len(definition) - 1
+	if last < 1 || definition[0] != definition[last] || (definition[0] != '!' && definition[0] != '+') {
+		return abc.UserSymbol{}, fmt.Errorf("invalid decoration %q for symbol %s", definition, symbol)
+	}
+	return abc.UserSymbol{Symbol: symbol, Decoration: parseDecoration(definition[1:last])}, nil
+}
+
+// isUserSymbol reports whether c may be redefined by a U: field.
+func isUserSymbol(c byte) bool {
+	return c >= 'H' && c <= 'W' || c >= 'h' && c <= 'w' || c == '~'
+}
+
+// withDecorations returns n with the given decorations added. It returns
+// false if n cannot be decorated.
+func withDecorations(n abc.Notation, decorations []abc.Decoration) (abc.Notation, bool) {
+	switch n := n.(type) {
+	case abc.Note:
+		n.Decorations = append(n.Decorations, decorations...)
+		return n, true
+	case abc.Chord:
+		n.Decorations = append(n.Decorations, decorations...)
+		return n, true
+	case abc.Rest:
+		n.Decorations = append(n.Decorations, decorations...)
+		return n, true
+	}
+	return n, false
+}
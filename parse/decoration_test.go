@@ -0,0 +1,89 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+func TestParseUserSymbol(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected abc.UserSymbol
+	}{
+		{
+			input:    "T = !turn!",
+			expected: abc.UserSymbol{Symbol: "T", Decoration: abc.DecorationTurn},
+		},
+		{
+			input:    "h=!emphasis!",
+			expected: abc.UserSymbol{Symbol: "h", Decoration: abc.DecorationAccent},
+		},
+		{
+			input:    "~ = +roll+",
+			expected: abc.UserSymbol{Symbol: "~", Decoration: abc.DecorationRoll},
+		},
+		{
+			input:    "W = !nil!",
+			expected: abc.UserSymbol{Symbol: "W"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := parseUserSymbol(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(test.expected, got) {
+				t.Errorf("symbols did not match: %v", cmp.Diff(test.expected, got))
+			}
+		})
+	}
+}
+
+func TestParseUserSymbolErrors(t *testing.T) {
+	for _, input := range []string{"T !turn!", "A = !turn!", "x = !turn!", "TT = !turn!", "T = turn", "T = !turn+"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseUserSymbol(input); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadUserSymbols(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`U:T = !turn!
+U:w = !fermata!
+
+X:1
+K:C
+TA wB ~c|
+
+X:2
+U:T = !mordent!
+U:~ = !nil!
+K:C
+TA wB ~c|
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][][]abc.Decoration
+	for _, tune := range tunes {
+		var decorations [][]abc.Decoration
+		for _, n := range tune.Bars[0].Notation {
+			decorations = append(decorations, n.(abc.Note).Decorations)
+		}
+		got = append(got, decorations)
+	}
+	expected := [][][]abc.Decoration{
+		{{abc.DecorationTurn}, {abc.DecorationFermata}, {abc.DecorationRoll}},
+		{{abc.DecorationLowerMordent}, {abc.DecorationFermata}, nil},
+	}
+	if !cmp.Equal(expected, got) {
+		t.Errorf("decorations did not match: %v", cmp.Diff(expected, got))
+	}
+}
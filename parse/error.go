@@ -0,0 +1,100 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseError describes a problem found while parsing ABC text, along with
+// where in the input it was found.
+type ParseError struct {
+	File     string   // Name of the input, if known
+	Line     int      // Line number, starting at 1
+	Column   int      // Column in characters, starting at 1
+	Offset   int      // Offset in bytes from the start of the input
+	Token    string   // Text of the offending token, if any
+	Expected []string // Kinds of token that would have been accepted, if any
+	Msg      string   // Description of the problem
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
+
+// errorf returns a ParseError for a problem found at the given item.
+func (p *parser) errorf(item *item, format string, args ...interface{}) *ParseError {
+	err := &ParseError{
+		File: p.name,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+	if item == nil {
+		// The input ended unexpectedly, so report the last position seen
+		item = p.last
+		err.Msg = fmt.Sprintf("%s at end of input", err.Msg)
+	} else if item.typ != itemError && item.typ != itemEOF {
+		err.Token = item.val
+	}
+	if item != nil {
+		err.Line = p.line + item.line
+		err.Column = item.col
+		err.Offset = p.offset + int(item.pos)
+	}
+	return err
+}
+
+// unexpected returns a ParseError for an item that is not one of the
+// expected types.
+func (p *parser) unexpected(item *item, expected ...itemType) *ParseError {
+	names := make([]string, len(expected))
+	for i, t := range expected {
+		names[i] = t.String()
+	}
+
+	var err *ParseError
+	switch {
+	case item == nil:
+		err = p.errorf(item, "expected %s", strings.Join(names, " or "))
+	case item.typ == itemNewline || item.typ == itemEOF:
+		err = p.errorf(item, "unexpected %s, expected %s", item.typ, strings.Join(names, " or "))
+	default:
+		err = p.errorf(item, "unexpected %s %v, expected %s", item.typ, item, strings.Join(names, " or "))
+	}
+	err.Expected = names
+	return err
+}
+
+// Diagnostic records an error found by ReadTolerant, which continued
+// parsing after the error.
+type Diagnostic struct {
+	Sequence int // Sequence number of the tune containing the error, 0 if outside any tune
+	*ParseError
+}
+
+// recover records err as a diagnostic and skips to the start of the next
+// line, from where parsing can continue.
+func (p *parser) recover(err error) {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		parseErr = p.errorf(p.last, "%v", err)
+	}
+	diagnostic := Diagnostic{ParseError: parseErr}
+	if p.currentTune != nil {
+		diagnostic.Sequence = p.currentTune.Sequence
+	}
+	p.diagnostics = append(p.diagnostics, diagnostic)
+
+	if p.peeked == nil && p.last != nil && p.last.typ == itemNewline {
+		// The error was at the end of the line, which has been consumed
+		p.lineEmpty = true
+		return
+	}
+	// A line containing an error does not count as blank
+	p.lineEmpty = false
+	for item := p.peek(); item != nil && item.typ != itemNewline && item.typ != itemEOF; item = p.peek() {
+		p.next()
+	}
+}
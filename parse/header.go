@@ -0,0 +1,104 @@
+package parse
+
+import (
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// startHeader begins the file header. Its fields are parsed into a tune
+// that is never returned, from which the header is taken once it ends.
+func (p *parser) startHeader() {
+	p.currentTune = &abc.Tune{}
+	p.inHeader = true
+}
+
+// fileHeader returns the fields of t that may be given in a file header.
+func fileHeader(t abc.Tune) abc.FileHeader {
+	return abc.FileHeader{
+		Area:          t.Area,
+		Book:          t.Book,
+		Composer:      t.Composer,
+		Discography:   t.Discography,
+		FileURL:       t.FileURL,
+		History:       t.History,
+		Group:         t.Group,
+		Comments:      t.Comments,
+		Rhythm:        t.Rhythm,
+		Origin:        t.Origin,
+		Meter:         t.Meter,
+		NoteLength:    t.NoteLength,
+		Key:           t.Key,
+		Clef:          t.Clef,
+		Source:        t.Source,
+		Transcription: t.Transcription,
+		Directives:    t.Directives,
+		UserSymbols:   t.UserSymbols,
+	}
+}
+
+// newTune returns a tune with the given sequence number, taking its
+// defaults from the file header. Fields in the tune header replace these
+// as they are parsed.
+func (p *parser) newTune(sequence int) *abc.Tune {
+	h := p.header
+	return &abc.Tune{
+		Area:          h.Area,
+		Book:          h.Book,
+		Composer:      h.Composer,
+		Discography:   h.Discography,
+		FileURL:       h.FileURL,
+		Group:         h.Group,
+		Sequence:      sequence,
+		Rhythm:        h.Rhythm,
+		Origin:        h.Origin,
+		Meter:         h.Meter,
+		NoteLength:    h.NoteLength,
+		Key:           h.Key,
+		Clef:          h.Clef,
+		Source:        h.Source,
+		Transcription: h.Transcription,
+		Directives:    append([]abc.Directive(nil), h.Directives...),
+		UserSymbols:   append([]abc.UserSymbol(nil), h.UserSymbols...),
+	}
+}
+
+// inheritLists applies the repeatable fields of the file header to the
+// current tune, unless the tune gave any of its own.
+func (p *parser) inheritLists() {
+	if len(p.currentTune.History) == 0 {
+		p.currentTune.History = p.header.History
+	}
+	if len(p.currentTune.Comments) == 0 {
+		p.currentTune.Comments = p.header.Comments
+	}
+}
+
+// addDirective handles a directive from an I: field or a %% line. In the
+// body of a tune, the directive is kept with the bar it is given in.
+func (p *parser) addDirective(value string, field bool) {
+	if p.currentTune == nil {
+		if p.headerDone {
+			// Directives between tunes are ignored
+			return
+		}
+		p.startHeader()
+	}
+	directive := parseDirective(value)
+	directive.Field = field
+	if p.inBody {
+		p.currentBar.Directives = append(p.currentBar.Directives, directive)
+		return
+	}
+	p.currentTune.Directives = append(p.currentTune.Directives, directive)
+}
+
+// parseDirective splits a directive such as "pagewidth 21cm" into its
+// name and value.
+func parseDirective(value string) abc.Directive {
+	value = strings.TrimSpace(value)
+	if end := strings.IndexAny(value, " \t"); end >= 0 {
+		return abc.Directive{Name: value[:end], Value: strings.TrimSpace(value[end:])}
+	}
+	return abc.Directive{Name: value}
+}
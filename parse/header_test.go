@@ -0,0 +1,200 @@
+package parse
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+const headerInput = `%abc-2.1
+%%pagewidth 21cm
+I:linebreak $
+M:6/8
+L:1/8
+R:jig
+H:History of the collection
+K:D
+
+X:1
+T:Inherits
+K:D
+
+X:2
+T:Overrides
+M:9/8
+R:slip jig
+H:History of the tune
+%%scale 0.8
+K:G
+`
+
+func TestFileHeader(t *testing.T) {
+	expectedHeader := abc.FileHeader{
+		History:    []string{"History of the collection"},
+		Rhythm:     "jig",
+		Meter:      abc.Meter{Numerator: []int{6}, Denominator: 8},
+		NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+		Key:        abc.Key{Tonic: 'D'},
+		Directives: []abc.Directive{
+			{Name: "pagewidth", Value: "21cm"},
+			{Name: "linebreak", Value: "$", Field: true},
+		},
+	}
+	expectedTunes := []abc.Tune{
+		{
+			Sequence:   1,
+			Title:      "Inherits",
+			History:    []string{"History of the collection"},
+			Rhythm:     "jig",
+			Meter:      abc.Meter{Numerator: []int{6}, Denominator: 8},
+			NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+			Key:        abc.Key{Tonic: 'D'},
+			Directives: []abc.Directive{
+				{Name: "pagewidth", Value: "21cm"},
+				{Name: "linebreak", Value: "$", Field: true},
+			},
+		},
+		{
+			Sequence:   2,
+			Title:      "Overrides",
+			History:    []string{"History of the tune"},
+			Rhythm:     "slip jig",
+			Meter:      abc.Meter{Numerator: []int{9}, Denominator: 8},
+			NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+			Key:        abc.Key{Tonic: 'G'},
+			Directives: []abc.Directive{
+				{Name: "pagewidth", Value: "21cm"},
+				{Name: "linebreak", Value: "$", Field: true},
+				{Name: "scale", Value: "0.8"},
+			},
+		},
+	}
+
+	tunes, err := Read(strings.NewReader(headerInput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmp.Equal(expectedTunes, tunes) {
+		t.Errorf("tunes did not match: %v", cmp.Diff(expectedTunes, tunes))
+	}
+
+	d := NewDecoder(strings.NewReader(headerInput))
+	var tune abc.Tune
+	for err = d.Decode(&tune); err == nil; err = d.Decode(&tune) {
+	}
+	if err != io.EOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := d.Header(); !cmp.Equal(expectedHeader, got) {
+		t.Errorf("header did not match: %v", cmp.Diff(expectedHeader, got))
+	}
+}
+
+func TestFieldsBetweenTunes(t *testing.T) {
+	input := "X:1\nK:G\n\nT:Not a tune\n%%ignored\n\nX:2\nK:D\n"
+	tunes, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eighth := abc.NoteLength{Numerator: 1, Denominator: 8}
+	expected := []abc.Tune{
+		{Sequence: 1, NoteLength: eighth, NoteLengthInferred: true, Key: abc.Key{Tonic: 'G'}},
+		{Sequence: 2, NoteLength: eighth, NoteLengthInferred: true, Key: abc.Key{Tonic: 'D'}},
+	}
+	if !cmp.Equal(expected, tunes) {
+		t.Errorf("tunes did not match: %v", cmp.Diff(expected, tunes))
+	}
+}
+
+func TestFileHeaderStartingWithDirective(t *testing.T) {
+	input := "%%pagewidth 21cm\nK:D clef=bass\n\nX:1\nK:G\n"
+	expected := []abc.Tune{
+		{
+			Sequence:           1,
+			NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+			NoteLengthInferred: true,
+			Key:                abc.Key{Tonic: 'G'},
+			Clef:               abc.Clef{Type: abc.ClefBass},
+			Directives:         []abc.Directive{{Name: "pagewidth", Value: "21cm"}},
+		},
+	}
+
+	tunes, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmp.Equal(expected, tunes) {
+		t.Errorf("tunes did not match: %v", cmp.Diff(expected, tunes))
+	}
+
+	var tune abc.Tune
+	if err := NewDecoder(strings.NewReader(input)).Decode(&tune); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmp.Equal(expected[0], tune) {
+		t.Errorf("decoded tune did not match: %v", cmp.Diff(expected[0], tune))
+	}
+}
+
+func TestBodyDirectives(t *testing.T) {
+	input := "X:1\n%%scale 0.8\nK:G\nG|\n%%vskip 1cm\nA[I:MIDI transpose 2]B|\n%%newpage\n"
+	tunes, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	single := abc.BarLine{Type: abc.BarLineSingle}
+	note := func(pitch abc.Pitch) abc.Note {
+		return abc.Note{Pitch: pitch, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}}
+	}
+	expected := abc.Tune{
+		Sequence:           1,
+		NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+		NoteLengthInferred: true,
+		Key:                abc.Key{Tonic: 'G'},
+		Directives:         []abc.Directive{{Name: "scale", Value: "0.8"}},
+		Bars: []abc.Bar{
+			{Notation: []abc.Notation{note('G')}, Right: single},
+			{
+				Directives: []abc.Directive{
+					{Name: "vskip", Value: "1cm"},
+					{Name: "MIDI", Value: "transpose 2", Field: true},
+				},
+				Left:     single,
+				Notation: []abc.Notation{note('A'), note('B')},
+				Right:    single,
+			},
+			{Directives: []abc.Directive{{Name: "newpage"}}, Left: single},
+		},
+	}
+	if !cmp.Equal(expected, tunes[0]) {
+		t.Errorf("tune did not match: %v", cmp.Diff(expected, tunes[0]))
+	}
+}
+
+func TestBodyTitles(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+T:Suite
+K:G
+G|
+T:Second movement
+B|
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := tunes[0]
+	if got.Title != "Suite" {
+		t.Errorf("expected the title of the tune to be kept, got %q", got.Title)
+	}
+	var titles []string
+	for _, bar := range got.Bars {
+		titles = append(titles, bar.Title)
+	}
+	if expected := []string{"", "Second movement"}; !cmp.Equal(expected, titles) {
+		t.Errorf("titles did not match: %v", cmp.Diff(expected, titles))
+	}
+}
@@ -0,0 +1,152 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// modes maps the significant first three letters of each mode name to
+// the mode. A lone "m" is also accepted for minor.
+var modes = map[string]abc.Mode{
+	"maj": abc.ModeMajor,
+	"min": abc.ModeMinor,
+	"m":   abc.ModeMinor,
+	"ion": abc.ModeIonian,
+	"aeo": abc.ModeAeolian,
+	"mix": abc.ModeMixolydian,
+	"dor": abc.ModeDorian,
+	"phr": abc.ModePhrygian,
+	"lyd": abc.ModeLydian,
+	"loc": abc.ModeLocrian,
+}
+
+// parseMode returns the mode named by s, which is matched without regard
+// to case on its first three letters.
+func parseMode(s string) (abc.Mode, bool) {
+	s = strings.ToLower(s)
+	if len(s) > 3 {
+		s = s[:3]
+	}
+	mode, ok := modes[s]
+	return mode, ok
+}
+
+// parseKey parses the value of a K: field, such as "Am", "F#mix",
+// "D exp ^f _b" or "none", along with any clef modifiers that follow.
+func parseKey(value string) (abc.Key, abc.Clef, error) {
+	key := abc.Key{
+		Tonic: 'C',
+	}
+	clef := abc.Clef{}
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return key, clef, nil
+	}
+
+	first := fields[0]
+	fields = fields[1:]
+	switch {
+	case strings.EqualFold(first, "none"):
+		key = abc.Key{Type: abc.KeyNone}
+	case first == "HP":
+		key = abc.Key{Type: abc.KeyHighlandPipes}
+	case first == "Hp":
+		key = abc.Key{Type: abc.KeyHighlandPipesSignature}
+	case first[0] >= 'A' && first[0] <= 'G':
+		key.Tonic = abc.Pitch(first[0])
+		rest := first[1:]
+		if strings.HasPrefix(rest, "#") {
+			key.Accidental = abc.AccidentalSharp
+			rest = rest[1:]
+		} else if strings.HasPrefix(rest, "b") {
+			key.Accidental = abc.AccidentalFlat
+			rest = rest[1:]
+		}
+
+		// The mode may follow the tonic directly, or as a separate word
+		if rest == "" && len(fields) > 0 {
+			if _, ok := parseMode(fields[0]); ok {
+				rest = fields[0]
+				fields = fields[1:]
+			}
+		}
+		if rest != "" {
+			mode, ok := parseMode(rest)
+			if !ok {
+				return key, clef, fmt.Errorf("unknown mode %q", rest)
+			}
+			key.Mode = mode
+		}
+		if sharps := key.Sharps(); sharps > 7 || sharps < -7 {
+			return key, clef, fmt.Errorf("unsupported key %q", value)
+		}
+	case isClefField(first):
+		// The key may be omitted when only the clef is given
+		fields = append([]string{first}, fields...)
+	default:
+		// A key may consist only of explicit accidentals
+		fields = append([]string{first}, fields...)
+		key = abc.Key{Type: abc.KeyNone}
+	}
+
+	for _, field := range fields {
+		switch {
+		case strings.EqualFold(field, "exp"):
+			key.Explicit = true
+		case strings.ContainsAny(field[:1], "^_="):
+			accidental, err := parseKeyAccidental(field)
+			if err != nil {
+				return key, clef, err
+			}
+			key.Accidentals = append(key.Accidentals, accidental)
+		case isClefField(field):
+			if err := parseClefField(&clef, field); err != nil {
+				return key, clef, err
+			}
+		default:
+			return key, clef, fmt.Errorf("unexpected %q in key", field)
+		}
+	}
+	return key, clef, nil
+}
+
+// keyOmitted reports whether the value of a K: field gives no key, only
+// a clef or nothing at all.
+func keyOmitted(value string) bool {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return true
+	}
+	first := fields[0]
+	switch {
+	case strings.EqualFold(first, "none"), first == "HP", first == "Hp":
+		return false
+	case first[0] >= 'A' && first[0] <= 'G':
+		return false
+	}
+	return isClefField(first)
+}
+
+// parseKeyAccidental parses an explicit accidental such as ^f or _b.
+func parseKeyAccidental(field string) (abc.KeyAccidental, error) {
+	accidental := abc.KeyAccidental{}
+	switch field[0] {
+	case '^':
+		accidental.Accidental = abc.AccidentalSharp
+	case '_':
+		accidental.Accidental = abc.AccidentalFlat
+	case '=':
+		accidental.Accidental = abc.AccidentalNatural
+	}
+	if len(field) != 2 {
+		return accidental, fmt.Errorf("invalid accidental %q in key", field)
+	}
+	pitch := strings.ToUpper(field[1:])[0]
+	if pitch < 'A' || pitch > 'G' {
+		return accidental, fmt.Errorf("invalid accidental %q in key", field)
+	}
+	accidental.Pitch = abc.Pitch(pitch)
+	return accidental, nil
+}
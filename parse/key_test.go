@@ -0,0 +1,216 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+func TestParseKey(t *testing.T) {
+	var tests = []struct {
+		input     string
+		expected  abc.Key
+		signature map[abc.Pitch]abc.Accidental
+	}{
+		{
+			input:     "C",
+			expected:  abc.Key{Tonic: 'C'},
+			signature: map[abc.Pitch]abc.Accidental{},
+		},
+		{
+			input:     "Am",
+			expected:  abc.Key{Tonic: 'A', Mode: abc.ModeMinor},
+			signature: map[abc.Pitch]abc.Accidental{},
+		},
+		{
+			input:    "F#mix",
+			expected: abc.Key{Tonic: 'F', Accidental: abc.AccidentalSharp, Mode: abc.ModeMixolydian},
+			signature: map[abc.Pitch]abc.Accidental{
+				'F': abc.AccidentalSharp, 'C': abc.AccidentalSharp, 'G': abc.AccidentalSharp,
+				'D': abc.AccidentalSharp, 'A': abc.AccidentalSharp,
+			},
+		},
+		{
+			input:    "Bb Dorian",
+			expected: abc.Key{Tonic: 'B', Accidental: abc.AccidentalFlat, Mode: abc.ModeDorian},
+			signature: map[abc.Pitch]abc.Accidental{
+				'B': abc.AccidentalFlat, 'E': abc.AccidentalFlat, 'A': abc.AccidentalFlat,
+				'D': abc.AccidentalFlat,
+			},
+		},
+		{
+			input: "D exp ^f _b",
+			expected: abc.Key{
+				Tonic:    'D',
+				Explicit: true,
+				Accidentals: []abc.KeyAccidental{
+					{Pitch: 'F', Accidental: abc.AccidentalSharp},
+					{Pitch: 'B', Accidental: abc.AccidentalFlat},
+				},
+			},
+			signature: map[abc.Pitch]abc.Accidental{
+				'F': abc.AccidentalSharp, 'B': abc.AccidentalFlat,
+			},
+		},
+		{
+			input: "G =f ^d",
+			expected: abc.Key{
+				Tonic: 'G',
+				Accidentals: []abc.KeyAccidental{
+					{Pitch: 'F', Accidental: abc.AccidentalNatural},
+					{Pitch: 'D', Accidental: abc.AccidentalSharp},
+				},
+			},
+			signature: map[abc.Pitch]abc.Accidental{
+				'D': abc.AccidentalSharp,
+			},
+		},
+		{
+			input:    "Hp",
+			expected: abc.Key{Type: abc.KeyHighlandPipesSignature},
+			signature: map[abc.Pitch]abc.Accidental{
+				'F': abc.AccidentalSharp, 'C': abc.AccidentalSharp,
+			},
+		},
+		{
+			input:     "HP",
+			expected:  abc.Key{Type: abc.KeyHighlandPipes},
+			signature: map[abc.Pitch]abc.Accidental{},
+		},
+		{
+			input:     "none",
+			expected:  abc.Key{Type: abc.KeyNone},
+			signature: map[abc.Pitch]abc.Accidental{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, _, err := parseKey(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(test.expected, got) {
+				t.Errorf("keys did not match: %v", cmp.Diff(test.expected, got))
+			}
+			if signature := got.Signature(); !cmp.Equal(test.signature, signature) {
+				t.Errorf("signatures did not match: %v", cmp.Diff(test.signature, signature))
+			}
+		})
+	}
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	for _, input := range []string{"Cfoo", "G#", "D exp ^h", "C clef=bass9", "C middle=h"} {
+		t.Run(input, func(t *testing.T) {
+			if _, _, err := parseKey(input); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseKeyClef(t *testing.T) {
+	var tests = []struct {
+		input    string
+		key      abc.Key
+		expected abc.Clef
+	}{
+		{
+			input:    "G",
+			key:      abc.Key{Tonic: 'G'},
+			expected: abc.Clef{},
+		},
+		{
+			input:    "Am clef=bass",
+			key:      abc.Key{Tonic: 'A', Mode: abc.ModeMinor},
+			expected: abc.Clef{Type: abc.ClefBass},
+		},
+		{
+			input:    "D alto1 middle=c",
+			key:      abc.Key{Tonic: 'D'},
+			expected: abc.Clef{Type: abc.ClefAlto, Line: 1, Middle: abc.StaffPitch{Pitch: 'C', Octave: 1}},
+		},
+		{
+			input: "Bb treble-8 transpose=-2 octave=-1 stafflines=4",
+			key:   abc.Key{Tonic: 'B', Accidental: abc.AccidentalFlat},
+			expected: abc.Clef{
+				Type:       abc.ClefTreble,
+				OctaveMark: -1,
+				Transpose:  -2,
+				Octave:     -1,
+				StaffLines: 4,
+			},
+		},
+		{
+			input:    "clef=perc m=B,",
+			key:      abc.Key{Tonic: 'C'},
+			expected: abc.Clef{Type: abc.ClefPercussion, Middle: abc.StaffPitch{Pitch: 'B', Octave: -1}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			key, got, err := parseKey(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(test.key, key) {
+				t.Errorf("keys did not match: %v", cmp.Diff(test.key, key))
+			}
+			if !cmp.Equal(test.expected, got) {
+				t.Errorf("clefs did not match: %v", cmp.Diff(test.expected, got))
+			}
+		})
+	}
+}
+
+func TestReadKeyChanges(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+Q:120
+K:D
+A>[K:G]B [K:clef=bass]|
+K:Am
+c|
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := tunes[0]
+
+	// Changes of key in the body leave the header as it was given
+	if expected := (abc.Key{Tonic: 'D'}); !cmp.Equal(expected, got.Key) {
+		t.Errorf("key did not match: %v", cmp.Diff(expected, got.Key))
+	}
+	if got.Clef != (abc.Clef{}) {
+		t.Errorf("expected no clef, got %v", got.Clef)
+	}
+	expectedTempo := abc.Tempo{Beat: []abc.NoteLength{{Numerator: 1, Denominator: 8}}, BPM: 120}
+	if !cmp.Equal(expectedTempo, got.Tempo) {
+		t.Errorf("tempo did not match: %v", cmp.Diff(expectedTempo, got.Tempo))
+	}
+
+	single := abc.BarLine{Type: abc.BarLineSingle}
+	expected := []abc.Bar{
+		{
+			Notation: []abc.Notation{
+				abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 3, Denominator: 2}, Broken: 1},
+				abc.KeyChange{Key: &abc.Key{Tonic: 'G'}},
+				abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 2}},
+				abc.KeyChange{Clef: abc.Clef{Type: abc.ClefBass}},
+			},
+			Right: single,
+		},
+		{
+			Left: single,
+			Notation: []abc.Notation{
+				abc.KeyChange{Key: &abc.Key{Tonic: 'A', Mode: abc.ModeMinor}},
+				abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+			},
+			Right: single,
+		},
+	}
+	if !cmp.Equal(expected, got.Bars) {
+		t.Errorf("bars did not match: %v", cmp.Diff(expected, got.Bars))
+	}
+}
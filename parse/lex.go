@@ -1,6 +1,7 @@
 package parse
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"unicode"
@@ -13,6 +14,7 @@ type item struct {
 	pos  Pos      // The starting position, in bytes, of this item in the input string.
 	val  string   // The value of this item.
 	line int      // The line number at the start of this item.
+	col  int      // The column, in runes, at the start of this item.
 }
 
 func (i item) String() string {
@@ -44,6 +46,14 @@ const (
 	itemVoice
 	itemOpenParen
 	itemCloseParen
+	itemDottedSlur
+	itemDottedTie
+	itemOpenBracket
+	itemCloseBracket
+	itemOpenBrace
+	itemCloseBrace
+	itemInlineField
+	itemInlineFieldEnd
 	itemLetter
 	itemNumber
 	itemDivide
@@ -54,10 +64,14 @@ const (
 	itemSharp
 	itemNatural
 	itemFlat
+	itemOctaveUp
+	itemOctaveDown
 
 	itemMinor
 	itemExclamation
 	itemStar
+	itemDecoration
+	itemSymbol
 
 	itemPercent
 
@@ -97,6 +111,79 @@ const (
 	itemEOF
 )
 
+// itemNames describes each item type for use in error messages.
+var itemNames = map[itemType]string{
+	itemError:                  "error",
+	itemFieldName:              "field name",
+	itemColon:                  "colon",
+	itemString:                 "string",
+	itemURL:                    "URL",
+	itemUnit:                   "unit",
+	itemKey:                    "key",
+	itemMeter:                  "meter",
+	itemMacro:                  "macro",
+	itemVoice:                  "voice",
+	itemOpenParen:              "(",
+	itemCloseParen:             ")",
+	itemDottedSlur:             ".(",
+	itemDottedTie:              ".-",
+	itemOpenBracket:            "[",
+	itemCloseBracket:           "]",
+	itemOpenBrace:              "{",
+	itemCloseBrace:             "}",
+	itemInlineField:            "inline field",
+	itemInlineFieldEnd:         "end of inline field",
+	itemLetter:                 "letter",
+	itemNumber:                 "number",
+	itemDivide:                 "/",
+	itemPlus:                   "+",
+	itemMinus:                  "-",
+	itemEquals:                 "=",
+	itemSharp:                  "^",
+	itemNatural:                "=",
+	itemFlat:                   "_",
+	itemOctaveUp:               "'",
+	itemOctaveDown:             ",",
+	itemMinor:                  "minor",
+	itemExclamation:            "!",
+	itemStar:                   "*",
+	itemDecoration:             "decoration",
+	itemSymbol:                 "symbol",
+	itemPercent:                "%",
+	itemDottedBarline:          "dotted bar line",
+	itemBarline:                "bar line",
+	itemThinThickDoubleBarLine: "thin-thick double bar line",
+	itemThinThinDoubleBarLine:  "thin-thin double bar line",
+	itemThickThinDoubleBarLine: "thick-thin double bar line",
+	itemStartRepeat:            "start repeat",
+	itemEndRepeat:              "end repeat",
+	itemStartEndRepeats:        "start and end repeat",
+	itemQuote:                  "quote",
+	itemNewline:                "newline",
+	itemSpace:                  "space",
+	itemBackslash:              "backslash",
+	itemGreaterThan:            ">",
+	itemLessThan:               "<",
+	itemComment:                "comment",
+	itemInvisibleRest:          "invisible rest",
+	itemRest:                   "rest",
+	itemMultiMeasureRest:       "multi-measure rest",
+	itemChord:                  "chord",
+	itemAnnotationPosition:     "annotation position",
+	itemAnnotation:             "annotation",
+	itemVariantNumber:          "variant number",
+	itemVariantComma:           "variant comma",
+	itemVariantRange:           "variant range",
+	itemEOF:                    "EOF",
+}
+
+func (t itemType) String() string {
+	if name, ok := itemNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("item(%d)", int(t))
+}
+
 const eof = -1
 
 // stateFn represents the state of the scanner as a function that returns the next state.
@@ -104,14 +191,21 @@ type stateFn func(*lexer) stateFn
 
 // lexer holds the state of the scanner.
 type lexer struct {
-	name       string     // the name of the input; used only for error reports
-	input      string     // the string being scanned
-	pos        Pos        // current position in the input
-	start      Pos        // start position of this item
-	width      Pos        // width of last rune read from input
-	items      chan *item // channel of scanned items
-	parenDepth int        // nesting depth of ( ) exprs
-	line       int        // 1+number of newlines seen
+	name       string          // the name of the input; used only for error reports
+	input      string          // the string being scanned
+	state      stateFn         // the next state to run, or nil once the scan is complete
+	pos        Pos             // current position in the input
+	start      Pos             // start position of this item
+	width      Pos             // width of last rune read from input
+	items      []*item         // items scanned but not yet returned
+	head       int             // index of the next item to return from items
+	free       []item          // storage for items yet to be scanned
+	parenDepth int             // nesting depth of ( ) exprs
+	line       int             // 1+number of newlines before counted
+	lineStart  Pos             // position of the start of the line containing counted
+	counted    Pos             // position up to which newlines have been counted
+	inline     bool            // true when scanning an inline field such as [K:G]
+	done       <-chan struct{} // closed once the scan should stop
 }
 
 // next returns the next rune in the input.
@@ -123,9 +217,6 @@ func (l *lexer) next() rune {
 	r, w := utf8.DecodeRuneInString(l.input[l.pos:])
 	l.width = Pos(w)
 	l.pos += l.width
-	if r == '\n' {
-		l.line++
-	}
 	return r
 }
 
@@ -139,21 +230,45 @@ func (l *lexer) peek() rune {
 // backup steps back one rune. Can only be called once per call of next.
 func (l *lexer) backup() {
 	l.pos -= l.width
-	// Correct newline count.
-	if l.width == 1 && l.input[l.pos] == '\n' {
-		l.line--
+}
+
+// position returns the line and column at the start of the pending input,
+// counting newlines since the previous call.
+func (l *lexer) position() (line, col int) {
+	for ; l.counted < l.start; l.counted++ {
+		if l.input[l.counted] == '\n' {
+			l.line++
+			l.lineStart = l.counted + 1
+		}
 	}
+	return l.line, utf8.RuneCountInString(l.input[l.lineStart:l.start]) + 1
 }
 
 // emit passes an item back to the client.
 func (l *lexer) emit(t itemType) {
-	l.items <- &item{t, l.start, l.input[l.start:l.pos], l.line}
+	l.add(t, l.input[l.start:l.pos])
 	l.start = l.pos
 }
 
+// add queues an item starting at l.start to be returned by nextItem.
+// Items are allocated in blocks to reduce the work of the garbage
+// collector; each is still safe for the parser to keep.
+func (l *lexer) add(t itemType, val string) {
+	if len(l.free) == 0 {
+		l.free = make([]item, itemBlockSize)
+	}
+	i := &l.free[0]
+	l.free = l.free[1:]
+	line, col := l.position()
+	*i = item{t, l.start, val, line, col}
+	l.items = append(l.items, i)
+}
+
+// itemBlockSize is the number of items allocated together by the lexer.
+const itemBlockSize = 256
+
 // ignore skips over the pending input before this point.
 func (l *lexer) ignore() {
-	l.line += strings.Count(l.input[l.start:l.pos], "\n")
 	l.start = l.pos
 }
 
@@ -173,44 +288,54 @@ func (l *lexer) acceptRun(valid string) {
 	l.backup()
 }
 
-// errorf returns an error token and terminates the scan by passing
-// back a nil pointer that will be the next state, terminating l.nextItem.
+// errorf returns an error token and skips the rest of the line, passing
+// back the state that resumes the scan at the start of the next line.
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
-	l.items <- &item{itemError, l.start, fmt.Sprintf(format, args...), l.line}
-	return nil
+	l.add(itemError, fmt.Sprintf(format, args...))
+	l.inline = false
+	l.consumeToEndOfLine()
+	l.ignore()
+	return lexLine
 }
 
-// nextItem returns the next item from the input.
-// Called by the parser, not in the lexing goroutine.
+// nextItem returns the next item from the input, or nil once the input
+// is exhausted or the scan has been stopped. The lexer only scans as much
+// of the input as is needed to return the item.
 func (l *lexer) nextItem() *item {
-	return <-l.items
+	for l.head == len(l.items) {
+		if l.state == nil {
+			return nil
+		}
+		select {
+		case <-l.done:
+			l.stop()
+			return nil
+		default:
+		}
+		l.items, l.head = l.items[:0], 0
+		l.state = l.state(l)
+	}
+	i := l.items[l.head]
+	l.head++
+	return i
 }
 
-// drain drains the output so the lexing goroutine will exit.
-// Called by the parser, not in the lexing goroutine.
-func (l *lexer) drain() {
-	for range l.items {
-	}
+// stop stops the scan, so no further items are returned.
+func (l *lexer) stop() {
+	l.state = nil
+	l.items, l.head = nil, 0
 }
 
-// lex creates a new scanner for the input string.
-func lex(name, input string) *lexer {
-	l := &lexer{
+// lex creates a new scanner for the input string. The scan stops when ctx
+// is done or stop is called, whichever happens first.
+func lex(ctx context.Context, name, input string) *lexer {
+	return &lexer{
 		name:  name,
 		input: input,
-		items: make(chan *item),
+		state: lexLine,
 		line:  1,
+		done:  ctx.Done(),
 	}
-	go l.run()
-	return l
-}
-
-// run runs the state machine for the lexer.
-func (l *lexer) run() {
-	for state := lexLine; state != nil; {
-		state = state(l)
-	}
-	close(l.items)
 }
 
 // state functions
@@ -244,6 +369,8 @@ const (
 	headerX = 'X' // Sequence number
 	headerZ = 'Z' // Transcription
 
+	headerContinuation = '+' // Continuation of the previous field
+
 	colon = ":"
 )
 
@@ -262,7 +389,7 @@ func (l *lexer) ignoreWhitespace() {
 func (l *lexer) consumeToEndOfLine() {
 	for true {
 		r := l.peek()
-		if !isEndOfLine(r) && !(r == eof) {
+		if !l.isEndOfField(r) {
 			l.pos++
 		} else {
 			return
@@ -270,6 +397,15 @@ func (l *lexer) consumeToEndOfLine() {
 	}
 }
 
+// isEndOfField reports whether r ends the value of a field. For inline
+// fields this is the closing bracket, otherwise the end of the line.
+func (l *lexer) isEndOfField(r rune) bool {
+	if l.inline {
+		return r == ']' || r == eof || isEndOfLine(r)
+	}
+	return r == eof || isEndOfLine(r)
+}
+
 func (l *lexer) acceptNewline() {
 	r := l.next()
 	if r == eof {
@@ -279,6 +415,9 @@ func (l *lexer) acceptNewline() {
 		l.errorf("expected newline, got %v", r)
 		return
 	}
+	if r == '\r' {
+		l.accept("\n")
+	}
 	l.emit(itemNewline)
 }
 
@@ -299,12 +438,29 @@ func lexLine(l *lexer) stateFn {
 		l.emit(itemEOF)
 		return nil
 	}
-	if strings.HasPrefix(l.input[l.pos+1:], colon) {
+	if r := l.peek(); (isFieldName(r) || r == headerContinuation) && strings.HasPrefix(l.input[l.pos+1:], colon) {
 		return lexHeaderLine
 	}
 	return lexBodyLine
 }
 
+// barLines lists the multi-character bar lines, longest first so that
+// shorter forms do not match the start of a longer one.
+var barLines = []struct {
+	text string
+	typ  itemType
+}{
+	{":||:", itemStartEndRepeats},
+	{":|:", itemStartEndRepeats},
+	{"::", itemStartEndRepeats},
+	{"|]", itemThinThickDoubleBarLine},
+	{"||", itemThinThinDoubleBarLine},
+	{"[|", itemThickThinDoubleBarLine},
+	{"|:", itemStartRepeat},
+	{":|", itemEndRepeat},
+	{".|", itemDottedBarline},
+}
+
 func lexBodyLine(l *lexer) stateFn {
 	if isEndOfLine(l.peek()) {
 		l.acceptNewline()
@@ -314,50 +470,17 @@ func lexBodyLine(l *lexer) stateFn {
 	if unicode.IsDigit(l.peek()) {
 		l.acceptDecimalRun()
 		l.emit(itemNumber)
-		return lexLine
+		return lexBodyLine
 	}
 
-	if len(l.input)-2 > int(l.pos) {
-		if strings.HasPrefix(l.input[l.pos+1:], "|]") {
-			l.pos += 2
-			l.emit(itemThinThickDoubleBarLine)
-			return lexBodyLine
-		}
-		if strings.HasPrefix(l.input[l.pos+1:], "||") {
-			l.pos += 2
-			l.emit(itemThinThinDoubleBarLine)
-			return lexBodyLine
-		}
-		if strings.HasPrefix(l.input[l.pos+1:], "[|") {
-			l.pos += 2
-			l.emit(itemThickThinDoubleBarLine)
-			return lexBodyLine
-		}
-		if strings.HasPrefix(l.input[l.pos+1:], "|:") {
-			l.pos += 2
-			l.emit(itemStartRepeat)
-			return lexBodyLine
+	for _, b := range barLines {
+		if strings.HasPrefix(l.input[l.pos:], b.text) {
+			l.pos += Pos(len(b.text))
+			l.emit(b.typ)
+			return lexAfterBarLine
 		}
-		if strings.HasPrefix(l.input[l.pos+1:], ":|") {
-			l.pos += 2
-			l.emit(itemEndRepeat)
-			return lexBodyLine
-		}
-		if strings.HasPrefix(l.input[l.pos+1:], "::") ||
-			strings.HasPrefix(l.input[l.pos+1:], ":|:") ||
-			strings.HasPrefix(l.input[l.pos+1:], ":||:") {
-			l.pos += 2
-			l.emit(itemStartEndRepeats)
-			return lexBodyLine
-		}
-		if strings.HasPrefix(l.input[l.pos+1:], ".|") {
-			l.pos += 2
-			l.emit(itemDottedBarline)
-			return lexBodyLine
-		}
-		// TODO: Support multiple repeats
-
 	}
+	// TODO: Support multiple repeats
 
 	c := l.next()
 	switch {
@@ -380,6 +503,10 @@ func lexBodyLine(l *lexer) stateFn {
 		l.emit(itemNatural)
 	case c == '_':
 		l.emit(itemFlat)
+	case c == '\'':
+		l.emit(itemOctaveUp)
+	case c == ',':
+		l.emit(itemOctaveDown)
 	case c == '/':
 		l.emit(itemDivide)
 	case c == '\\':
@@ -390,7 +517,26 @@ func lexBodyLine(l *lexer) stateFn {
 		return lexChord
 	case c == '|':
 		l.emit(itemBarline)
+		return lexAfterBarLine
 
+	case c == '(':
+		l.emit(itemOpenParen)
+		if unicode.IsDigit(l.peek()) {
+			return lexTuplet
+		}
+	case c == ')':
+		l.emit(itemCloseParen)
+	case c == '.' && l.peek() == '(':
+		l.pos++
+		l.emit(itemDottedSlur)
+	case c == '.' && l.peek() == '-':
+		l.pos++
+		l.emit(itemDottedTie)
+	case c == '.' || c == '~':
+		l.emit(itemSymbol)
+	case c == '!':
+		l.ignore()
+		return lexDecoration
 	case c == '+':
 		l.emit(itemPlus)
 	case c == '<':
@@ -404,15 +550,49 @@ func lexBodyLine(l *lexer) stateFn {
 			l.ignore()
 			return lexVariant
 		}
-		l.errorf("unexpected character after [")
+		if isFieldName(l.peek()) && strings.HasPrefix(l.input[l.pos+1:], colon) {
+			l.ignore()
+			return lexInlineField
+		}
+		l.emit(itemOpenBracket)
+	case c == ']':
+		l.emit(itemCloseBracket)
+	case c == '{':
+		l.emit(itemOpenBrace)
+	case c == '}':
+		l.emit(itemCloseBrace)
 	case c == ':':
 		l.emit(itemColon)
 	case c == eof:
 		l.emit(itemEOF)
 		return nil
 	default:
-		l.errorf("unknown character: %c", c)
-		return nil
+		return l.errorf("unknown character: %c", c)
+	}
+	return lexBodyLine
+}
+
+// lexTuplet scans the numbers of a tuplet, such as (3:2:4. The colons are
+// lexed here so that (3::4 is not taken for a bar line.
+func lexTuplet(l *lexer) stateFn {
+	l.acceptDecimalRun()
+	l.emit(itemNumber)
+	for i := 0; i < 2 && l.peek() == ':'; i++ {
+		l.pos++
+		l.emit(itemColon)
+		if unicode.IsDigit(l.peek()) {
+			l.acceptDecimalRun()
+			l.emit(itemNumber)
+		}
+	}
+	return lexBodyLine
+}
+
+// lexAfterBarLine scans the list of variant endings that may follow a bar
+// line, such as |1 or :|2,3.
+func lexAfterBarLine(l *lexer) stateFn {
+	if unicode.IsDigit(l.peek()) {
+		return lexVariant
 	}
 	return lexBodyLine
 }
@@ -433,7 +613,7 @@ func lexVariant(l *lexer) stateFn {
 		return lexVariant
 	}
 
-	return lexLine
+	return lexBodyLine
 }
 
 func lexChord(l *lexer) stateFn {
@@ -453,6 +633,9 @@ func lexChord(l *lexer) stateFn {
 		if r == '"' {
 			break
 		}
+		if r == eof || isEndOfLine(r) {
+			return l.errorf("unterminated chord")
+		}
 		l.pos++
 	}
 	l.emit(itemChord)
@@ -461,12 +644,34 @@ func lexChord(l *lexer) stateFn {
 	return lexBodyLine
 }
 
+// lexDecoration scans the name of a decoration such as !trill!, following
+// the opening exclamation mark.
+func lexDecoration(l *lexer) stateFn {
+	for true {
+		r := l.peek()
+		if r == '!' {
+			break
+		}
+		if r == eof || isEndOfLine(r) {
+			return l.errorf("unterminated decoration")
+		}
+		l.pos++
+	}
+	l.emit(itemDecoration)
+	l.pos++
+	l.ignore()
+	return lexBodyLine
+}
+
 func lexAnnotation(l *lexer) stateFn {
 	for true {
 		r := l.peek()
 		if r == '"' {
 			break
 		}
+		if r == eof || isEndOfLine(r) {
+			return l.errorf("unterminated annotation")
+		}
 		l.pos++
 	}
 	l.emit(itemAnnotation)
@@ -482,10 +687,22 @@ func lexComment(l *lexer) stateFn {
 	return lexLine
 }
 
+// lexInlineField scans a field within the body of a tune, such as [V:1].
+func lexInlineField(l *lexer) stateFn {
+	l.inline = true
+	return lexField(l, itemInlineField)
+}
+
 func lexHeaderLine(l *lexer) stateFn {
+	return lexField(l, itemFieldName)
+}
+
+// lexField scans the name of a field, emitting it as the given item type,
+// and returns the state to scan its value.
+func lexField(l *lexer, typ itemType) stateFn {
 	fieldName := l.next()
 
-	l.emit(itemFieldName)
+	l.emit(typ)
 	// Skip the colon
 	l.pos++
 	l.ignore()
@@ -493,7 +710,10 @@ func lexHeaderLine(l *lexer) stateFn {
 	switch fieldName {
 	case headerX:
 		return lexHeaderInt
-	case headerT, headerN, headerC, headerO, headerR:
+	case headerA, headerB, headerC, headerD, headerF, headerG, headerH,
+		headerI, headerm, headerN, headerO, headerP, headerQ, headerR,
+		headerS, headers, headerT, headerU, headerV, headerW, headerw,
+		headerZ, headerContinuation:
 		return lexHeaderString
 	case headerr:
 		return lexHeaderRemark
@@ -502,22 +722,31 @@ func lexHeaderLine(l *lexer) stateFn {
 	case headerL:
 		return lexHeaderNoteLength
 	case headerK:
-		// TODO: Implement something for the key
-		return lexHeaderString
+		return lexHeaderKey
 	default:
-		l.errorf("unknown header field %s", string(fieldName))
-		return nil
+		return l.errorf("unknown header field %s", string(fieldName))
 	}
 }
 
 func lexNextLine(l *lexer) stateFn {
 	l.ignoreWhitespace()
+	if l.inline {
+		l.inline = false
+		if !l.accept("]") {
+			return l.errorf("unterminated inline field")
+		}
+		l.emit(itemInlineFieldEnd)
+		return lexBodyLine
+	}
 	l.acceptNewline()
 	return lexLine
 }
 
 func lexHeaderInt(l *lexer) stateFn {
 	l.ignoreWhitespace()
+	if !unicode.IsDigit(l.peek()) {
+		return l.errorf("expected number")
+	}
 	l.acceptDecimalRun()
 	l.emit(itemNumber)
 	return lexNextLine
@@ -537,33 +766,34 @@ func lexHeaderString(l *lexer) stateFn {
 	return lexNextLine
 }
 
+// lexHeaderKey emits the value of a key field.
+func lexHeaderKey(l *lexer) stateFn {
+	l.emitValue(itemKey)
+	return lexNextLine
+}
+
+// lexHeaderMeter emits the value of a meter field.
 func lexHeaderMeter(l *lexer) stateFn {
+	l.emitValue(itemMeter)
+	return lexNextLine
+}
+
+// emitValue emits the value of a field as the given item type, without
+// any trailing comment.
+func (l *lexer) emitValue(t itemType) {
 	l.ignoreWhitespace()
-	c := l.peek()
-	for !isEndOfLine(c) {
+	for r := l.peek(); !l.isEndOfField(r) && r != '%'; r = l.peek() {
 		l.pos++
-		switch {
-		case unicode.IsDigit(c):
-			l.acceptDecimalRun()
-			l.emit(itemNumber)
-		case c == '(':
-			l.emit(itemOpenParen)
-		case c == ')':
-			l.emit(itemCloseParen)
-		case c == '+':
-			l.emit(itemPlus)
-		case c == '/':
-			l.emit(itemDivide)
-		}
-		c = l.peek()
 	}
-	return lexNextLine
+	l.emit(t)
+	l.consumeToEndOfLine()
+	l.ignore()
 }
 
 func lexHeaderNoteLength(l *lexer) stateFn {
 	l.ignoreWhitespace()
 	c := l.peek()
-	for !isEndOfLine(c) {
+	for !l.isEndOfField(c) {
 		l.pos++
 		switch {
 		case unicode.IsLetter(c):
@@ -574,8 +804,7 @@ func lexHeaderNoteLength(l *lexer) stateFn {
 		case c == '/':
 			l.emit(itemDivide)
 		default:
-			l.errorf("unknown character: %c", c)
-			return nil
+			return l.errorf("unknown character: %c", c)
 		}
 		c = l.peek()
 	}
@@ -591,6 +820,12 @@ func isSpace(r rune) bool {
 	return r == ' ' || r == '\t'
 }
 
+// isFieldName reports whether r names an information field. Other
+// letters followed by a colon are music, such as the rest in z:|.
+func isFieldName(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || r == headerm || r == headerr || r == headers || r == headerw
+}
+
 // isEndOfLine reports whether r is an end-of-line character.
 func isEndOfLine(r rune) bool {
 	return r == '\r' || r == '\n'
@@ -1,8 +1,8 @@
 package parse
 
 import (
+	"context"
 	"testing"
-	"time"
 )
 
 func TestLexItems(t *testing.T) {
@@ -28,12 +28,42 @@ O:Irish
 R:Reel`,
 			expected: []itemType{
 				itemFieldName, itemNumber, itemNewline,
-				itemFieldName, itemNumber, itemDivide, itemNumber, itemNewline,
+				itemFieldName, itemMeter, itemNewline,
 				itemFieldName, itemString, itemNewline,
 				itemFieldName, itemString,
 				itemEOF,
 			},
 		},
+		{
+			name: "handles key with comment",
+			file: `K:D exp ^f _b % comment
+A`,
+			expected: []itemType{
+				itemFieldName, itemKey, itemNewline,
+				itemLetter,
+				itemEOF,
+			},
+		},
+		{
+			name: "handles inline fields",
+			file: `a[V:T1 clef=bass]b[K:G]`,
+			expected: []itemType{
+				itemLetter,
+				itemInlineField, itemString, itemInlineFieldEnd,
+				itemLetter,
+				itemInlineField, itemKey, itemInlineFieldEnd,
+				itemEOF,
+			},
+		},
+		{
+			name: "resumes after error",
+			file: "a$b|\nc",
+			expected: []itemType{
+				itemLetter, itemError, itemNewline,
+				itemLetter,
+				itemEOF,
+			},
+		},
 		{
 			name: "handles body line",
 			file: `eg|a2ab ageg|`,
@@ -51,7 +81,7 @@ O:Irish
 R:Reel
 eg|a2ab ageg|`,
 			expected: []itemType{
-				itemFieldName, itemNumber, itemDivide, itemNumber, itemNewline,
+				itemFieldName, itemMeter, itemNewline,
 				itemFieldName, itemString, itemNewline,
 				itemFieldName, itemString, itemNewline,
 				itemLetter, itemLetter, itemBarline,
@@ -79,25 +109,111 @@ ageg|`,
 				itemEOF,
 			},
 		},
+		{
+			name: "handles bar lines",
+			file: `|:a:|b||c|]d[|e::f.|g|1`,
+			expected: []itemType{
+				itemStartRepeat, itemLetter, itemEndRepeat,
+				itemLetter, itemThinThinDoubleBarLine,
+				itemLetter, itemThinThickDoubleBarLine,
+				itemLetter, itemThickThinDoubleBarLine,
+				itemLetter, itemStartEndRepeats,
+				itemLetter, itemDottedBarline,
+				itemLetter, itemBarline, itemVariantNumber,
+				itemEOF,
+			},
+		},
+		{
+			name: "lexes music that starts a line like a field",
+			file: "z:|\nb::a",
+			expected: []itemType{
+				itemRest, itemEndRepeat, itemNewline,
+				itemLetter, itemStartEndRepeats, itemLetter,
+				itemEOF,
+			},
+		},
+		{
+			name: "handles octave marks",
+			file: `c'C,,`,
+			expected: []itemType{
+				itemLetter, itemOctaveUp, itemLetter, itemOctaveDown, itemOctaveDown,
+				itemEOF,
+			},
+		},
+		{
+			name: "handles tuplets",
+			file: `(3abc(3:2:4(3::2`,
+			expected: []itemType{
+				itemOpenParen, itemNumber, itemLetter, itemLetter, itemLetter,
+				itemOpenParen, itemNumber, itemColon, itemNumber, itemColon, itemNumber,
+				itemOpenParen, itemNumber, itemColon, itemColon, itemNumber,
+				itemEOF,
+			},
+		},
+		{
+			name: "handles grace notes",
+			file: `{g}A{/ge}B`,
+			expected: []itemType{
+				itemOpenBrace, itemLetter, itemCloseBrace, itemLetter,
+				itemOpenBrace, itemDivide, itemLetter, itemLetter, itemCloseBrace, itemLetter,
+				itemEOF,
+			},
+		},
+		{
+			name: "handles ties and slurs",
+			file: `(A-B) .(c.-c)`,
+			expected: []itemType{
+				itemOpenParen, itemLetter, itemMinus, itemLetter, itemCloseParen, itemSpace,
+				itemDottedSlur, itemLetter, itemDottedTie, itemLetter, itemCloseParen,
+				itemEOF,
+			},
+		},
+		{
+			name: "handles decorations",
+			file: `!trill!A~.B!p!|`,
+			expected: []itemType{
+				itemDecoration, itemLetter, itemSymbol, itemSymbol, itemLetter, itemDecoration, itemBarline,
+				itemEOF,
+			},
+		},
+		{
+			name: "handles chords and variants",
+			file: `[CEG]2 [1,3 a`,
+			expected: []itemType{
+				itemOpenBracket, itemLetter, itemLetter, itemLetter, itemCloseBracket, itemNumber,
+				itemSpace, itemVariantNumber, itemVariantComma, itemVariantNumber,
+				itemSpace, itemLetter,
+				itemEOF,
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			l := lex(test.name, test.file)
+			l := lex(context.Background(), test.name, test.file)
 			for _, expected := range test.expected {
-				select {
-				case got := <-l.items:
-					if got.typ != expected {
-						t.Errorf("expected type %d, got %d", expected, got.typ)
-					} else {
-						t.Logf("got %d", got.typ)
-					}
-				case <-time.After(time.Second):
+				got := l.nextItem()
+				if got == nil {
 					t.Errorf("Ran out of items, expecting %d", expected)
+				} else if got.typ != expected {
+					t.Errorf("expected type %d, got %d", expected, got.typ)
+				} else {
+					t.Logf("got %d", got.typ)
 				}
 			}
-			for got := range l.items {
+			for got := l.nextItem(); got != nil; got = l.nextItem() {
 				t.Errorf("unexpected token '%v'", got)
 			}
 		})
 	}
 }
+
+func BenchmarkLex(b *testing.B) {
+	book := tuneBook(1000)
+	b.SetBytes(int64(len(book)))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		l := lex(context.Background(), "", book)
+		for item := l.nextItem(); item != nil; item = l.nextItem() {
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package parse
+
+import (
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// lyricMark records a position in the music of a tune, so lyrics can be
+// aligned to the notes that follow it.
+type lyricMark struct {
+	voiceID  string
+	bar      int // index of the bar within the voice
+	notation int // index of the notation within that bar
+}
+
+// lyric is a single syllable of a w: field, or a bar line.
+type lyric struct {
+	syllable abc.Syllable
+	bar      bool
+}
+
+// splitLyrics splits the value of a w: field into syllables.
+func splitLyrics(value string) []lyric {
+	var lyrics []lyric
+	var text strings.Builder
+	pending := false
+	end := func(hyphen bool) {
+		if pending || hyphen {
+			lyrics = append(lyrics, lyric{syllable: abc.Syllable{Text: text.String(), Hyphen: hyphen}})
+		}
+		text.Reset()
+		pending = false
+	}
+
+	escaped := false
+	for _, r := range value {
+		if escaped {
+			escaped = false
+			text.WriteRune(r)
+			pending = true
+			continue
+		}
+		switch r {
+		case ' ', '\t':
+			end(false)
+		case '-':
+			end(true)
+		case '_':
+			end(false)
+			lyrics = append(lyrics, lyric{syllable: abc.Syllable{Hold: true}})
+		case '*':
+			end(false)
+			lyrics = append(lyrics, lyric{})
+		case '|':
+			end(false)
+			lyrics = append(lyrics, lyric{bar: true})
+		case '~':
+			text.WriteRune(' ')
+			pending = true
+		case '\\':
+			escaped = true
+		default:
+			text.WriteRune(r)
+			pending = true
+		}
+	}
+	end(false)
+	return lyrics
+}
+
+// markLine records the current position as the start of a line of music.
+func (p *parser) markLine() {
+	p.lineStart = lyricMark{
+		voiceID:  p.voiceID,
+		bar:      len(*p.bars()),
+		notation: len(p.currentBar.Notation),
+	}
+}
+
+// addLyrics handles a w: field, aligning its syllables to the notes of the
+// preceding line of music. Each further w: field adds another verse.
+func (p *parser) addLyrics() error {
+	value, err := p.expectString()
+	if err != nil {
+		return err
+	}
+	if p.lyricsStart == nil {
+		// No music precedes these lyrics
+		return nil
+	}
+
+	voiceID := p.voiceID
+	p.switchVoice(p.lyricsStart.voiceID)
+	defer p.switchVoice(voiceID)
+
+	bars := *p.bars()
+	var notation [][]abc.Notation
+	for i := p.lyricsStart.bar; i < len(bars); i++ {
+		notation = append(notation, bars[i].Notation)
+	}
+	notation = append(notation, p.currentBar.Notation)
+	lines := make([][]lyricSlot, len(notation))
+	for i := range notation {
+		lines[i] = lyricSlots(notation[i], nil)
+	}
+
+	// Notes before the start of the line have already been sung to
+	bar := 0
+	index := len(lyricSlots(notation[0][:p.lyricsStart.notation], nil))
+	for _, l := range splitLyrics(value) {
+		if l.bar {
+			if index > 0 {
+				bar, index = bar+1, 0
+			}
+			continue
+		}
+		// Find the next note, skipping bars without any
+		for bar < len(lines) && index >= len(lines[bar]) {
+			bar, index = bar+1, 0
+		}
+		if bar >= len(lines) {
+			break
+		}
+		slot := lines[bar][index]
+		slot.notation[slot.index] = withSyllable(slot.notation[slot.index], p.verse, l.syllable)
+		index++
+	}
+	p.verse++
+	return nil
+}
+
+// lyricSlot is the position of a note or chord that may have a syllable
+// sung to it.
+type lyricSlot struct {
+	notation []abc.Notation
+	index    int
+}
+
+// lyricSlots appends the positions of the notes and chords in notation to
+// slots, including those within tuplets.
+func lyricSlots(notation []abc.Notation, slots []lyricSlot) []lyricSlot {
+	for i, n := range notation {
+		switch v := n.(type) {
+		case abc.Note, abc.Chord:
+			slots = append(slots, lyricSlot{notation: notation, index: i})
+		case abc.Tuplet:
+			slots = lyricSlots(v.Notation, slots)
+		}
+	}
+	return slots
+}
+
+// withSyllable returns n with the syllable for the given verse set. Notes
+// skipped with a blank syllable are left unchanged.
+func withSyllable(n abc.Notation, verse int, syllable abc.Syllable) abc.Notation {
+	if syllable == (abc.Syllable{}) {
+		return n
+	}
+	set := func(lyrics []abc.Syllable) []abc.Syllable {
+		for len(lyrics) <= verse {
+			lyrics = append(lyrics, abc.Syllable{})
+		}
+		lyrics[verse] = syllable
+		return lyrics
+	}
+	switch v := n.(type) {
+	case abc.Note:
+		v.Lyrics = set(v.Lyrics)
+		return v
+	case abc.Chord:
+		v.Lyrics = set(v.Lyrics)
+		return v
+	}
+	return n
+}
@@ -0,0 +1,117 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+func TestSplitLyrics(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected []lyric
+	}{
+		{
+			input: "syll-a-ble",
+			expected: []lyric{
+				{syllable: abc.Syllable{Text: "syll", Hyphen: true}},
+				{syllable: abc.Syllable{Text: "a", Hyphen: true}},
+				{syllable: abc.Syllable{Text: "ble"}},
+			},
+		},
+		{
+			input: "time__ * of~the day | e\\-mail",
+			expected: []lyric{
+				{syllable: abc.Syllable{Text: "time"}},
+				{syllable: abc.Syllable{Hold: true}},
+				{syllable: abc.Syllable{Hold: true}},
+				{},
+				{syllable: abc.Syllable{Text: "of the"}},
+				{syllable: abc.Syllable{Text: "day"}},
+				{bar: true},
+				{syllable: abc.Syllable{Text: "e-mail"}},
+			},
+		},
+		{
+			input: "a -- b",
+			expected: []lyric{
+				{syllable: abc.Syllable{Text: "a"}},
+				{syllable: abc.Syllable{Hyphen: true}},
+				{syllable: abc.Syllable{Hyphen: true}},
+				{syllable: abc.Syllable{Text: "b"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got := splitLyrics(test.input)
+			if !cmp.Equal(test.expected, got, cmp.AllowUnexported(lyric{})) {
+				t.Errorf("lyrics did not match: %v", cmp.Diff(test.expected, got, cmp.AllowUnexported(lyric{})))
+			}
+		})
+	}
+}
+
+func TestReadLyrics(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+K:C
+C D z E|F G2|
+w:Hel-lo | my_
+w:Good-bye * | dear friend
+A B|
+w:end
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][]abc.Syllable
+	for _, bar := range tunes[0].Bars {
+		for _, n := range bar.Notation {
+			if note, ok := n.(abc.Note); ok {
+				got = append(got, note.Lyrics)
+			}
+		}
+	}
+	expected := [][]abc.Syllable{
+		{{Text: "Hel", Hyphen: true}, {Text: "Good", Hyphen: true}},
+		{{Text: "lo"}, {Text: "bye"}},
+		nil,
+		{{Text: "my"}, {Text: "dear"}},
+		{{Hold: true}, {Text: "friend"}},
+		{{Text: "end"}},
+		nil,
+	}
+	if !cmp.Equal(expected, got) {
+		t.Errorf("lyrics did not match: %v", cmp.Diff(expected, got))
+	}
+}
+
+func TestReadLyricsInTuplets(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+K:C
+(3CDE F|
+w:one two three four
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	notation := tunes[0].Bars[0].Notation
+	tuplet, ok := notation[0].(abc.Tuplet)
+	if !ok {
+		t.Fatalf("expected a tuplet, got %T", notation[0])
+	}
+	var got []string
+	for _, n := range append(tuplet.Notation, notation[1]) {
+		for _, syllable := range n.(abc.Note).Lyrics {
+			got = append(got, syllable.Text)
+		}
+	}
+	expected := []string{"one", "two", "three", "four"}
+	if !cmp.Equal(expected, got) {
+		t.Errorf("lyrics did not match: %v", cmp.Diff(expected, got))
+	}
+}
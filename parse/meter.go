@@ -0,0 +1,51 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// parseMeter parses the value of an M: field, such as 6/8, C, C|, none
+// or (2+3+2)/8.
+func parseMeter(value string) (abc.Meter, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "", "none":
+		return abc.Meter{Type: abc.MeterFree}, nil
+	case "C":
+		return abc.Meter{Type: abc.MeterCommon, Numerator: []int{4}, Denominator: 4}, nil
+	case "C|":
+		return abc.Meter{Type: abc.MeterCut, Numerator: []int{2}, Denominator: 2}, nil
+	}
+
+	meter := abc.Meter{}
+	numerator, denominator, ok := strings.Cut(value, "/")
+	if !ok {
+		return meter, fmt.Errorf("invalid meter %q", value)
+	}
+	numerator = strings.TrimSpace(numerator)
+	if strings.HasPrefix(numerator, "(") && strings.HasSuffix(numerator, ")") {
+		meter.Grouped = true
+		numerator = numerator[1 : len(numerator)-1]
+	}
+	for _, term := range strings.Split(numerator, "+") {
+		n, err := strconv.Atoi(strings.TrimSpace(term))
+		if err != nil || n <= 0 {
+			return meter, fmt.Errorf("invalid numerator %q in meter", term)
+		}
+		meter.Numerator = append(meter.Numerator, n)
+	}
+	denominator = strings.TrimSpace(denominator)
+	if denominator == "" {
+		return meter, fmt.Errorf("missing denominator in meter")
+	}
+	var err error
+	meter.Denominator, err = strconv.Atoi(denominator)
+	if err != nil || meter.Denominator <= 0 {
+		return meter, fmt.Errorf("invalid denominator %q in meter", denominator)
+	}
+	return meter, nil
+}
@@ -0,0 +1,147 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+func TestParseMeter(t *testing.T) {
+	var tests = []struct {
+		input       string
+		expected    abc.Meter
+		beatsPerBar int
+		barLength   abc.Duration
+	}{
+		{
+			input:       "6/8",
+			expected:    abc.Meter{Numerator: []int{6}, Denominator: 8},
+			beatsPerBar: 2,
+			barLength:   abc.Duration{Numerator: 3, Denominator: 4},
+		},
+		{
+			input:       "3/4",
+			expected:    abc.Meter{Numerator: []int{3}, Denominator: 4},
+			beatsPerBar: 3,
+			barLength:   abc.Duration{Numerator: 3, Denominator: 4},
+		},
+		{
+			input:       "C",
+			expected:    abc.Meter{Type: abc.MeterCommon, Numerator: []int{4}, Denominator: 4},
+			beatsPerBar: 4,
+			barLength:   abc.Duration{Numerator: 1, Denominator: 1},
+		},
+		{
+			input:       "C|",
+			expected:    abc.Meter{Type: abc.MeterCut, Numerator: []int{2}, Denominator: 2},
+			beatsPerBar: 2,
+			barLength:   abc.Duration{Numerator: 1, Denominator: 1},
+		},
+		{
+			input:     "none",
+			expected:  abc.Meter{Type: abc.MeterFree},
+			barLength: abc.Duration{Numerator: 0, Denominator: 1},
+		},
+		{
+			input:       "(2+3+2)/8",
+			expected:    abc.Meter{Numerator: []int{2, 3, 2}, Denominator: 8, Grouped: true},
+			beatsPerBar: 3,
+			barLength:   abc.Duration{Numerator: 7, Denominator: 8},
+		},
+		{
+			input:       "3+2/8",
+			expected:    abc.Meter{Numerator: []int{3, 2}, Denominator: 8},
+			beatsPerBar: 2,
+			barLength:   abc.Duration{Numerator: 5, Denominator: 8},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := parseMeter(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(test.expected, got) {
+				t.Errorf("meters did not match: %v", cmp.Diff(test.expected, got))
+			}
+			if beats := got.BeatsPerBar(); beats != test.beatsPerBar {
+				t.Errorf("expected %d beats per bar, got %d", test.beatsPerBar, beats)
+			}
+			if length := got.BarLength(); !cmp.Equal(test.barLength, length) {
+				t.Errorf("bar lengths did not match: %v", cmp.Diff(test.barLength, length))
+			}
+		})
+	}
+}
+
+func TestParseMeterErrors(t *testing.T) {
+	for _, input := range []string{"4/", "/4", "3", "a/4", "3/0", "(2+3/8"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseMeter(input); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadMeterChanges(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+M:9/8
+L:1/8
+K:C
+(5ABcde|
+M:2/4
+[L:1/16](5ABcde|
+V:2
+(5ABcde|
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := tunes[0]
+
+	// Changes in the body leave the header as it was given
+	if expected := (abc.Meter{Numerator: []int{9}, Denominator: 8}); !cmp.Equal(expected, got.Meter) {
+		t.Errorf("meter did not match: %v", cmp.Diff(expected, got.Meter))
+	}
+	if expected := (abc.NoteLength{Numerator: 1, Denominator: 8}); !cmp.Equal(expected, got.NoteLength) {
+		t.Errorf("note length did not match: %v", cmp.Diff(expected, got.NoteLength))
+	}
+
+	// The default time of a tuplet follows the meter of its voice
+	tuplet := func(q int) abc.Tuplet {
+		var notation []abc.Notation
+		for _, pitch := range "ABCDE" {
+			octave := 0
+			if pitch > 'B' {
+				octave = 1
+			}
+			notation = append(notation, abc.Note{Pitch: abc.Pitch(pitch), Octave: octave, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}})
+		}
+		return abc.Tuplet{P: 5, Q: q, R: 5, Notation: notation}
+	}
+	single := abc.BarLine{Type: abc.BarLineSingle}
+	expected := []abc.Bar{
+		{Notation: []abc.Notation{tuplet(3)}, Right: single},
+		{
+			Left: single,
+			Notation: []abc.Notation{
+				abc.MeterChange{Meter: abc.Meter{Numerator: []int{2}, Denominator: 4}},
+				abc.NoteLengthChange{NoteLength: abc.NoteLength{Numerator: 1, Denominator: 16}},
+				tuplet(2),
+			},
+			Right: single,
+		},
+	}
+	if !cmp.Equal(expected, got.Bars) {
+		t.Errorf("bars did not match: %v", cmp.Diff(expected, got.Bars))
+	}
+	expectedVoices := []abc.Voice{
+		{ID: "2", Bars: []abc.Bar{{Notation: []abc.Notation{tuplet(3)}, Right: single}}},
+	}
+	if !cmp.Equal(expectedVoices, got.Voices) {
+		t.Errorf("voices did not match: %v", cmp.Diff(expectedVoices, got.Voices))
+	}
+}
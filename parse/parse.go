@@ -1,68 +1,347 @@
 package parse
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"strconv"
+	"strings"
 
 	"github.com/theothertomelliott/abc"
 )
 
 // Read parses an input stream into a sequence of abc.Tune objects.
 // An error is returned in the event the stream cannot be parsed.
+//
+// The file header is applied to each tune but not returned; a Decoder is
+// the only way to read the file header itself, with its directives and
+// user symbols kept apart from those of the tunes.
 func Read(in io.Reader) ([]abc.Tune, error) {
+	return ReadContext(context.Background(), in)
+}
+
+// ReadContext is like Read, but stops parsing and returns the context's
+// error if ctx is done before parsing completes. The whole stream is read
+// before parsing starts, and reading is not interrupted by ctx.
+func ReadContext(ctx context.Context, in io.Reader) ([]abc.Tune, error) {
+	parser, err := newParser(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return parser.parse()
+}
+
+// ReadTolerant parses an input stream into a sequence of abc.Tune objects,
+// recovering from any errors in the ABC text. Each error is recorded as a
+// Diagnostic, and parsing resumes at the next line, or the next tune if the
+// error was in an X: field. An error is returned only if the stream cannot
+// be read. As with Read, the file header is applied to each tune but not
+// returned.
+func ReadTolerant(in io.Reader) ([]abc.Tune, []Diagnostic, error) {
+	parser, err := newParser(context.Background(), in)
+	if err != nil {
+		return nil, nil, err
+	}
+	parser.tolerant = true
+	tunes, err := parser.parse()
+	return tunes, parser.diagnostics, err
+}
+
+func newParser(ctx context.Context, in io.Reader) (*parser, error) {
 	file, err := ioutil.ReadAll(in)
 	if err != nil {
 		return nil, err
 	}
-	l := lex("filename", string(file))
-	parser := &parser{
-		lexer: l,
+	name := readerName(in)
+	return &parser{
+		ctx:       ctx,
+		name:      name,
+		lexer:     lex(ctx, name, string(file)),
+		lineEmpty: true,
+	}, nil
+}
+
+// readerName returns the name of the input, such as the name of a file,
+// for use in error reports.
+func readerName(in io.Reader) string {
+	if named, ok := in.(interface{ Name() string }); ok {
+		return named.Name()
 	}
-	return parser.parse()
+	return ""
 }
 
 type parser struct {
-	lexer       lexingResult
-	tunes       []abc.Tune
-	currentTune *abc.Tune
+	ctx           context.Context // done once parsing should stop
+	name          string          // name of the input; used only for error reports
+	lexer         lexingResult
+	line          int // lines in the stream before the lexer's input
+	offset        int // bytes in the stream before the lexer's input
+	tunes         []abc.Tune
+	currentTune   *abc.Tune
+	currentBar    abc.Bar               // bar being built from the tune body
+	inBody        bool                  // true once the header of the current tune has ended
+	voiceID       string                // voice receiving music, empty before the first voice
+	pendingVoices map[string]voiceState // music of voices other than voiceID, set aside until they resume
+	meter         abc.Meter             // meter in effect for the voice receiving music
+	noteLength    abc.NoteLength        // unit note length in effect for the voice receiving music
+	lineStart     lyricMark             // start of the current line of music
+	lineMusic     bool                  // true if the current line contains music
+	broken        abc.BrokenRhythm      // broken rhythm awaiting the next note or chord
+	beamBreak     bool                  // true if a space has been seen since the last notation
+	tuplets       []abc.Tuplet          // tuplets awaiting notes, innermost last
+	graceNotes    bool                  // true if grace notes await the note they precede
+	decorations   []abc.Decoration      // decorations awaiting the note, chord, rest or bar line they apply to
+	chordSymbols  []abc.ChordSymbol     // chord symbols awaiting the note, chord, rest or bar line they apply to
+	chordStart    item                  // the first of chordSymbols, for error reports
+	slurID        int                   // ID of the last slur started in the current tune
+	pendingSlurs  []abc.Slur            // slurs awaiting the note or chord they start at
+	openSlurs     []openSlur            // slurs started but not yet ended, innermost last
+	lyricsStart   *lyricMark            // start of the line that w: fields apply to
+	verse         int                   // number of w: fields applied to that line
+	peeked        *item                 // item returned by peek, not yet consumed
+	last          *item                 // item most recently returned by next
+	lineEmpty     bool                  // true if nothing has been seen on the current line
+	header        abc.FileHeader        // fields inherited by each tune
+	inHeader      bool                  // true while parsing the file header
+	headerDone    bool                  // true once the file header can no longer start
+	tolerant      bool                  // true to record errors and continue parsing
+	diagnostics   []Diagnostic          // errors recorded in tolerant mode
 }
 
 type lexingResult interface {
 	nextItem() *item
-	drain()
+	stop()
 }
 
 func (p *parser) parse() ([]abc.Tune, error) {
+	defer p.lexer.stop()
+	if err := p.parseItems(); err != nil {
+		return nil, err
+	}
+	if err := p.endTune(); err != nil {
+		return nil, err
+	}
+	return p.tunes, nil
+}
+
+// parseItems handles all items from the lexer, leaving any tune still in
+// progress to be continued by further input.
+func (p *parser) parseItems() error {
 	for true {
-		item := p.lexer.nextItem()
+		item := p.next()
 		if item == nil {
 			break
 		}
 		err := p.handleItem(item)
 		if err != nil {
-			return nil, err
+			if !p.tolerant {
+				return err
+			}
+			p.recover(err)
 		}
 	}
-	if p.currentTune != nil {
-		p.tunes = append(p.tunes, *p.currentTune)
+	// The lexer also stops early if the context is done
+	return p.ctx.Err()
+}
+
+// next returns the next item, either one previously peeked or a new
+// one from the lexer.
+func (p *parser) next() *item {
+	if p.peeked != nil {
+		item := p.peeked
+		p.peeked = nil
+		return item
 	}
-	return p.tunes, nil
+	item := p.lexer.nextItem()
+	if item != nil {
+		p.last = item
+	}
+	return item
+}
+
+// peek returns but does not consume the next item.
+func (p *parser) peek() *item {
+	if p.peeked == nil {
+		p.peeked = p.lexer.nextItem()
+		if p.peeked != nil {
+			p.last = p.peeked
+		}
+	}
+	return p.peeked
 }
 
 func (p *parser) handleItem(item *item) error {
-	if item.typ == itemFieldName {
+	switch item.typ {
+	case itemFieldName:
 		err := p.handleFieldName(item)
 		if err != nil {
 			return err
 		}
+		p.lineEmpty = true
+		return nil
+	case itemInlineField:
+		p.lineEmpty = false
+		return p.handleFieldName(item)
+	case itemError:
+		return p.errorf(item, "%s", item.val)
+	case itemComment:
+		if p.lineEmpty && strings.HasPrefix(item.val, "%") {
+			// A line starting %% is a directive
+			p.addDirective(item.val[1:], false)
+		}
+		// Comment lines are not blank, so do not end a tune
+		p.lineEmpty = false
+		return nil
+	case itemEOF, itemPercent:
+		return nil
+	case itemNewline:
+		// A blank line ends the current tune
+		if p.lineEmpty {
+			if err := p.endTune(); err != nil {
+				return err
+			}
+		}
+		if p.lineMusic {
+			start := p.lineStart
+			p.lyricsStart = &start
+			p.verse = 0
+			p.lineMusic = false
+		}
+		p.lineEmpty = true
+		return nil
 	}
-	return nil
+	p.lineEmpty = false
+	if p.currentTune == nil || p.inHeader {
+		// Free text between tunes
+		return nil
+	}
+	return p.handleBodyItem(item)
 }
 
+// endTune completes the tune currently being parsed, if any. The tune is
+// completed even if a slur in it was never closed, or chord symbols at its
+// end apply to nothing, which is reported afterwards.
+func (p *parser) endTune() error {
+	if p.currentTune == nil {
+		return nil
+	}
+	var unclosed *ParseError
+	if start := p.firstChordSymbol(); start != nil {
+		unclosed = p.errorf(start, "chord symbol must be followed by a note, chord, rest or bar line")
+	} else if slur := p.firstOpenSlur(); slur != nil {
+		unclosed = p.errorf(&slur.start, "unclosed slur")
+	}
+	sequence := p.currentTune.Sequence
+
+	if p.inHeader {
+		p.header = fileHeader(*p.currentTune)
+		p.inHeader = false
+		p.headerDone = true
+	} else {
+		if p.currentTune.Key.Type == abc.KeyStandard && p.currentTune.Key.Tonic == 0 {
+			// A tune with no K: field is in C, as if given K:C
+			p.currentTune.Key.Tonic = 'C'
+		}
+		p.resolveNoteLength()
+		p.resolveTempo()
+		p.completeVoice()
+		for id, state := range p.pendingVoices {
+			p.voiceID = id
+			p.restoreVoice(state)
+			p.completeVoice()
+		}
+		p.inheritLists()
+		p.tunes = append(p.tunes, *p.currentTune)
+	}
+	p.currentTune = nil
+	p.inBody = false
+	p.voiceID = ""
+	p.pendingVoices = nil
+	p.meter = abc.Meter{}
+	p.noteLength = abc.NoteLength{}
+	p.lineMusic = false
+	p.broken = 0
+	p.beamBreak = false
+	p.tuplets = nil
+	p.graceNotes = false
+	p.decorations = nil
+	p.chordSymbols = nil
+	p.slurID = 0
+	p.pendingSlurs = nil
+	p.openSlurs = nil
+	p.lyricsStart = nil
+
+	if unclosed == nil {
+		return nil
+	}
+	if p.tolerant {
+		p.diagnostics = append(p.diagnostics, Diagnostic{Sequence: sequence, ParseError: unclosed})
+		return nil
+	}
+	return unclosed
+}
+
+// completeVoice adds the music left incomplete at the end of a tune to the
+// voice receiving music. Tuplets left incomplete keep the notes they have.
+func (p *parser) completeVoice() {
+	for len(p.tuplets) > 0 {
+		last := len(p.tuplets) - 1
+		tuplet := p.tuplets[last]
+		p.tuplets = p.tuplets[:last]
+		p.appendNotation(tuplet)
+	}
+	p.flushBar()
+}
+
+// flushBar adds the current bar to the current voice, if it contains any
+// notation or directives, and starts a new bar.
+func (p *parser) flushBar() {
+	if len(p.currentBar.Notation) > 0 || len(p.currentBar.Directives) > 0 {
+		bars := p.bars()
+		*bars = append(*bars, p.currentBar)
+	}
+	p.currentBar = abc.Bar{}
+}
+
+// handleFieldName parses a field, reporting any problems with its value
+// at the position of the field.
 func (p *parser) handleFieldName(item *item) error {
+	if p.currentTune == nil && item.val != string(headerX) {
+		if p.headerDone {
+			// Fields between tunes are ignored
+			return p.consumeToNewline()
+		}
+		p.startHeader()
+	}
+	err := p.parseField(item)
+	var parseErr *ParseError
+	if err != nil && !errors.As(err, &parseErr) {
+		return p.errorf(item, "%s field: %v", item.val, err)
+	}
+	return err
+}
+
+// headerOnlyFields are the fields that may only be given in the header of
+// a tune, not among its music.
+var headerOnlyFields = map[string]bool{
+	string(headerA): true,
+	string(headerB): true,
+	string(headerC): true,
+	string(headerD): true,
+	string(headerF): true,
+	string(headerG): true,
+	string(headerH): true,
+	string(headerO): true,
+	string(headerS): true,
+	string(headerX): true,
+	string(headerZ): true,
+}
+
+func (p *parser) parseField(item *item) error {
+	if p.inBody && headerOnlyFields[item.val] {
+		return p.errorf(item, "%s field is not allowed in the tune body", item.val)
+	}
 	var err error
 	switch item.val {
 	case string(headerA):
@@ -86,11 +365,14 @@ func (p *parser) handleFieldName(item *item) error {
 	case string(headerH):
 		return p.addHistory()
 	case string(headerI):
-		// TODO
-		return p.consumeToNewline()
+		value, err := p.expectString()
+		if err != nil {
+			return err
+		}
+		p.addDirective(value, true)
+		return nil
 	case string(headerK):
-		// TODO
-		return p.consumeToNewline()
+		return p.setKey()
 	case string(headerL):
 		return p.setNoteLength()
 	case string(headerM):
@@ -104,11 +386,9 @@ func (p *parser) handleFieldName(item *item) error {
 		p.currentTune.Origin, err = p.expectString()
 		return err
 	case string(headerP):
-		// TODO
-		return p.consumeToNewline()
+		return p.setParts()
 	case string(headerQ):
-		// TODO
-		return p.consumeToNewline()
+		return p.setTempo()
 	case string(headerR):
 		p.currentTune.Rhythm, err = p.expectString()
 		return err
@@ -122,75 +402,82 @@ func (p *parser) handleFieldName(item *item) error {
 		// TODO
 		return p.consumeToNewline()
 	case string(headerT):
-		p.currentTune.Title, err = p.expectString()
-		return err
+		return p.setTitle()
 	case string(headerU):
-		// TODO
-		return p.consumeToNewline()
+		return p.addUserSymbol()
 	case string(headerV):
-		// TODO
-		return p.consumeToNewline()
+		return p.setVoice()
 	case string(headerW):
 		return p.addWordsAfterTune()
 	case string(headerw):
-		// TODO
-		return p.consumeToNewline()
+		return p.addLyrics()
 	case string(headerX):
 		return p.setSequence()
 	case string(headerZ):
 		p.currentTune.Transcription, err = p.expectString()
 		return err
+	case string(headerContinuation):
+		return p.errorf(item, "field continuation is not supported")
 	}
-	return errors.New("unhandled")
+	return p.errorf(item, "unknown field %s", item.val)
 }
 
 func (p *parser) consumeToNewline() error {
-	for item := p.lexer.nextItem(); item != nil && item.typ != itemNewline; item = p.lexer.nextItem() {
+	for item := p.next(); item != nil && item.typ != itemNewline && item.typ != itemInlineFieldEnd; item = p.next() {
+		if item.typ == itemEOF {
+			p.peeked = item
+			return nil
+		}
 	}
 	return nil
 }
 
+// expectNewline consumes the newline at the end of a line, or the end of
+// an inline field. The end of the input is also accepted, and left to be
+// read by the caller.
 func (p *parser) expectNewline() error {
-	_, err := p.expect(itemNewline)
-	return err
+	item, err := p.expect(itemNewline, itemInlineFieldEnd, itemEOF)
+	if err != nil {
+		return err
+	}
+	if item.typ == itemEOF {
+		p.peeked = item
+	}
+	return nil
 }
 
 func (p *parser) expect(types ...itemType) (*item, error) {
-	item := p.lexer.nextItem()
+	item := p.next()
 	if item == nil {
-		return nil, fmt.Errorf("expected one of %v, got nil token", types)
+		return nil, p.unexpected(item, types...)
 	}
 	for _, t := range types {
 		if item.typ == t {
 			return item, nil
 		}
 	}
-	return nil, fmt.Errorf("expected one of %v, got nil token", types)
+	if item.typ == itemError {
+		return nil, p.errorf(item, "%s", item.val)
+	}
+	return nil, p.unexpected(item, types...)
 }
 
 func (p *parser) setMeter() error {
-	meter := abc.Meter{}
-	// Numerator
-	for item := p.lexer.nextItem(); item != nil && item.typ != itemDivide; item = p.lexer.nextItem() {
-		switch item.typ {
-		case itemNumber:
-			numeratorValue, _ := strconv.Atoi(item.val)
-			meter.Numerator = append(meter.Numerator, numeratorValue)
-		case itemPlus:
-		default:
-			return fmt.Errorf("expected number or plus, got %v", item)
-		}
-	}
-
-	// Denominator
-	item, err := p.expect(itemNumber)
+	item, err := p.expect(itemMeter)
 	if err != nil {
 		return err
 	}
-	meter.Denominator, _ = strconv.Atoi(item.val)
-
+	meter, err := parseMeter(item.val)
+	if err != nil {
+		return p.errorf(item, "%v", err)
+	}
+	if p.inBody {
+		p.meter = meter
+		p.appendNotation(abc.MeterChange{Meter: meter})
+		return p.expectNewline()
+	}
 	p.currentTune.Meter = meter
-	return p.consumeToNewline()
+	return p.expectNewline()
 }
 
 func (p *parser) setNoteLength() error {
@@ -216,10 +503,127 @@ func (p *parser) setNoteLength() error {
 	}
 	noteLength.Denominator, _ = strconv.Atoi(item.val)
 
+	if p.inBody {
+		p.noteLength = noteLength
+		p.appendNotation(abc.NoteLengthChange{NoteLength: noteLength})
+		return p.consumeToNewline()
+	}
 	p.currentTune.NoteLength = noteLength
 	return p.consumeToNewline()
 }
 
+func (p *parser) setKey() error {
+	item, err := p.expect(itemKey)
+	if err != nil {
+		return err
+	}
+	key, clef, err := parseKey(item.val)
+	if err != nil {
+		return p.errorf(item, "%v", err)
+	}
+	if p.inBody {
+		// A change of key applies from this point in the music, leaving
+		// the key of the tune as given in its header
+		change := abc.KeyChange{Key: &key, Clef: clef}
+		if keyOmitted(item.val) {
+			change.Key = nil
+		}
+		p.appendNotation(change)
+		return p.expectNewline()
+	}
+	p.currentTune.Key = key
+	// A key with no clef keeps the clef already in use
+	if clef != (abc.Clef{}) {
+		if voice := p.findVoice(p.voiceID); voice != nil {
+			voice.Clef = clef
+		} else {
+			p.currentTune.Clef = clef
+		}
+	}
+	if p.inHeader {
+		return p.expectNewline()
+	}
+	// The key ends the header, so the unit note length is now known
+	p.resolveNoteLength()
+	p.resolveTempo()
+	p.meter = p.currentTune.Meter
+	p.noteLength = p.currentTune.NoteLength
+	p.inBody = true
+	return p.expectNewline()
+}
+
+// setTitle handles a T: field. In the header, this gives the title of the
+// tune. In the body, it marks the start of a section with its own title.
+func (p *parser) setTitle() error {
+	value, err := p.expectString()
+	if err != nil {
+		return err
+	}
+	if p.inBody {
+		p.currentBar.Title = value
+		return nil
+	}
+	p.currentTune.Title = value
+	return nil
+}
+
+// setParts handles a P: field. In the header, this gives the order in
+// which parts are played. In the body, it marks the start of a part.
+func (p *parser) setParts() error {
+	value, err := p.expectString()
+	if err != nil {
+		return err
+	}
+	if p.inBody {
+		p.currentBar.Part = strings.TrimSpace(value)
+		return nil
+	}
+	p.currentTune.Parts, err = parsePlayOrder(value)
+	return err
+}
+
+func (p *parser) setTempo() error {
+	value, err := p.expectString()
+	if err != nil {
+		return err
+	}
+	tempo, err := parseTempo(value)
+	if err != nil {
+		return err
+	}
+	if p.inBody {
+		if tempo.BPM != 0 && len(tempo.Beat) == 0 {
+			tempo.Beat = []abc.NoteLength{p.noteLength}
+		}
+		p.appendNotation(abc.TempoChange{Tempo: tempo})
+		return nil
+	}
+	p.currentTune.Tempo = tempo
+	return nil
+}
+
+// resolveTempo applies the unit note length to a tempo given in the
+// deprecated form with no beat, such as Q:120. The unit note length must
+// already be resolved.
+func (p *parser) resolveTempo() {
+	tempo := &p.currentTune.Tempo
+	if tempo.BPM == 0 || len(tempo.Beat) > 0 {
+		return
+	}
+	tempo.Beat = []abc.NoteLength{p.currentTune.NoteLength}
+}
+
+// resolveNoteLength derives the unit note length from the meter if the
+// tune has no L: field.
+func (p *parser) resolveNoteLength() {
+	tune := p.currentTune
+	if tune.NoteLength.Denominator != 0 {
+		return
+	}
+	tune.NoteLength = tune.Meter.DefaultNoteLength()
+	tune.NoteLengthInferred = true
+}
+
 func (p *parser) expectString() (string, error) {
 	item, err := p.expect(itemString)
 	if err != nil {
@@ -256,16 +660,17 @@ func (p *parser) addHistory() error {
 }
 
 func (p *parser) setSequence() error {
+	// End the previous tune first, so the rest of a tune with an invalid
+	// X: field is skipped rather than added to the previous one
+	if err := p.endTune(); err != nil {
+		return err
+	}
+	p.headerDone = true
 	item, err := p.expect(itemNumber)
 	if err != nil {
 		return err
 	}
-	if p.currentTune != nil {
-		p.tunes = append(p.tunes, *p.currentTune)
-	}
 	sequenceNum, _ := strconv.Atoi(item.val)
-	p.currentTune = &abc.Tune{
-		Sequence: sequenceNum,
-	}
+	p.currentTune = p.newTune(sequenceNum)
 	return p.expectNewline()
 }
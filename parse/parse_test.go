@@ -1,6 +1,10 @@
 package parse
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -35,7 +39,7 @@ func TestParse(t *testing.T) {
 			name: "parses headers appropriately",
 			input: []*item{
 				i(itemFieldName, "X"), i(itemNumber, "1"), newline(),
-				i(itemFieldName, "M"), i(itemNumber, "4"), i(itemPlus, "+"), i(itemNumber, "1"), i(itemDivide, "/"), i(itemNumber, "4"), newline(),
+				i(itemFieldName, "M"), i(itemMeter, "4+1/4"), newline(),
 				i(itemFieldName, "O"), i(itemString, "Irish"), newline(),
 				i(itemFieldName, "N"), i(itemString, "First comment line"), newline(),
 				i(itemFieldName, "R"), i(itemString, "Reel"), newline(),
@@ -66,6 +70,7 @@ func TestParse(t *testing.T) {
 						Numerator:   1,
 						Denominator: 4,
 					},
+					Key:            abc.Key{Tonic: 'C'},
 					Rhythm:         "Reel",
 					Origin:         "Irish",
 					Title:          "Wild Irish Rose",
@@ -90,6 +95,7 @@ func TestParse(t *testing.T) {
 				items: test.input,
 			}
 			p := parser{
+				ctx:   context.Background(),
 				lexer: lexer,
 			}
 			got, err := p.parse()
@@ -104,8 +110,9 @@ func TestParse(t *testing.T) {
 }
 
 type itemSource struct {
-	items []*item
-	pos   int
+	items   []*item
+	pos     int
+	stopped bool
 }
 
 func (i *itemSource) nextItem() *item {
@@ -117,4 +124,1046 @@ func (i *itemSource) nextItem() *item {
 	return i.items[index]
 }
 
-func (i *itemSource) drain() {}
+func (i *itemSource) stop() {
+	i.stopped = true
+}
+
+func TestRead(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected []abc.Tune
+	}{
+		{
+			name: "parses notes, rests and chords into bars",
+			input: `X:1
+T:Example
+K:G
+|:G2 ^F/2z/2 [GBd]2 x|A3/2 _B Z2:|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:           1,
+					Title:              "Example",
+					NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+					NoteLengthInferred: true,
+					Key:                abc.Key{Tonic: 'G'},
+					Bars: []abc.Bar{
+						{
+							Left: abc.BarLine{Type: abc.BarLineStartRepeat},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'G', Duration: abc.NoteLength{Numerator: 2, Denominator: 1}},
+								abc.Note{Pitch: 'F', Accidental: abc.AccidentalSharp, Duration: abc.NoteLength{Numerator: 1, Denominator: 2}, BeamBreak: true},
+								abc.Rest{Duration: abc.NoteLength{Numerator: 1, Denominator: 2}},
+								abc.Chord{
+									Notes: []abc.Note{
+										{Pitch: 'G', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+										{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+										{Pitch: 'D', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									},
+									Duration:  abc.NoteLength{Numerator: 2, Denominator: 1},
+									BeamBreak: true,
+								},
+								abc.Rest{Invisible: true, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineSingle},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 3, Denominator: 2}},
+								abc.Note{Pitch: 'B', Accidental: abc.AccidentalFlat, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+								abc.MultiMeasureRest{Bars: 2},
+							},
+							Right: abc.BarLine{Type: abc.BarLineEndRepeat},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "parses octave marks, accidentals and length shorthand",
+			input: `X:1
+L:1/8
+K:C
+C,, c'' ^^F __B =c ^/G _3/2e A/ B// c3/|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:   1,
+					NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+					Key:        abc.Key{Tonic: 'C'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'C', Octave: -2, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								abc.Note{Pitch: 'C', Octave: 3, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+								abc.Note{Pitch: 'F', Accidental: abc.AccidentalDoubleSharp, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+								abc.Note{Pitch: 'B', Accidental: abc.AccidentalDoubleFlat, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+								abc.Note{Pitch: 'C', Octave: 1, Accidental: abc.AccidentalNatural, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+								abc.Note{Pitch: 'G', Accidental: abc.AccidentalSharp, Microtone: abc.Microtone{Numerator: 1, Denominator: 2}, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+								abc.Note{Pitch: 'E', Octave: 1, Accidental: abc.AccidentalFlat, Microtone: abc.Microtone{Numerator: 3, Denominator: 2}, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+								abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 1, Denominator: 2}, BeamBreak: true},
+								abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 4}, BeamBreak: true},
+								abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 3, Denominator: 2}, BeamBreak: true},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "applies broken rhythm to adjacent notes",
+			input: `X:1
+L:1/8
+K:C
+A>B c2>>d e<<<f [CE]>G|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:   1,
+					NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+					Key:        abc.Key{Tonic: 'C'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 3, Denominator: 2}, Broken: 1},
+								abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 2}},
+								abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 7, Denominator: 2}, Broken: 2, BeamBreak: true},
+								abc.Note{Pitch: 'D', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 4}},
+								abc.Note{Pitch: 'E', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 8}, Broken: -3, BeamBreak: true},
+								abc.Note{Pitch: 'F', Octave: 1, Duration: abc.NoteLength{Numerator: 15, Denominator: 8}},
+								abc.Chord{
+									Notes: []abc.Note{
+										{Pitch: 'C', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+										{Pitch: 'E', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									},
+									Duration:  abc.NoteLength{Numerator: 3, Denominator: 2},
+									Broken:    1,
+									BeamBreak: true,
+								},
+								abc.Note{Pitch: 'G', Duration: abc.NoteLength{Numerator: 1, Denominator: 2}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "groups tuplets, taking defaults from the meter",
+			input: `X:1
+M:6/8
+L:1/8
+K:C
+(3abc (2de (5::3Bcd (3(3ABCDE|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:   1,
+					Meter:      abc.Meter{Numerator: []int{6}, Denominator: 8},
+					NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+					Key:        abc.Key{Tonic: 'C'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Tuplet{P: 3, Q: 2, R: 3, Notation: []abc.Notation{
+									abc.Note{Pitch: 'A', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									abc.Note{Pitch: 'B', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								}},
+								abc.Tuplet{P: 2, Q: 3, R: 2, BeamBreak: true, Notation: []abc.Notation{
+									abc.Note{Pitch: 'D', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									abc.Note{Pitch: 'E', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								}},
+								abc.Tuplet{P: 5, Q: 3, R: 3, BeamBreak: true, Notation: []abc.Notation{
+									abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									abc.Note{Pitch: 'D', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								}},
+								abc.Tuplet{P: 3, Q: 2, R: 3, BeamBreak: true, Notation: []abc.Notation{
+									abc.Tuplet{P: 3, Q: 2, R: 3, Notation: []abc.Notation{
+										abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+										abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+										abc.Note{Pitch: 'C', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									}},
+									abc.Note{Pitch: 'D', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									abc.Note{Pitch: 'E', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "places grace notes before their main note",
+			input: `X:1
+L:1/8
+K:C
+{g}A2 {/^fe}B (3{c}dcB|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:   1,
+					NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+					Key:        abc.Key{Tonic: 'C'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.GraceNotes{Notes: []abc.Note{
+									{Pitch: 'G', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								}},
+								abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 2, Denominator: 1}},
+								abc.GraceNotes{Acciaccatura: true, BeamBreak: true, Notes: []abc.Note{
+									{Pitch: 'F', Octave: 1, Accidental: abc.AccidentalSharp, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									{Pitch: 'E', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								}},
+								abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								abc.Tuplet{P: 3, Q: 2, R: 3, BeamBreak: true, Notation: []abc.Notation{
+									abc.GraceNotes{Notes: []abc.Note{
+										{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									}},
+									abc.Note{Pitch: 'D', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "pairs slurs across bar lines and lines",
+			input: `X:1
+L:1/8
+K:C
+(A-A ((B|c.-)c
+d) .(e[ce]-|[ce]))|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:   1,
+					NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+					Key:        abc.Key{Tonic: 'C'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Tie: abc.TieSolid, Slurs: []abc.Slur{{ID: 1}}},
+								abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Slurs: []abc.Slur{{ID: 2}, {ID: 3}}, BeamBreak: true},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineSingle},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Tie: abc.TieDotted, SlursEnded: []int{3}},
+								abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								abc.Note{Pitch: 'D', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, SlursEnded: []int{2}},
+								abc.Note{Pitch: 'E', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Slurs: []abc.Slur{{ID: 4, Dotted: true}}, BeamBreak: true},
+								abc.Chord{
+									Notes: []abc.Note{
+										{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Tie: abc.TieSolid},
+										{Pitch: 'E', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Tie: abc.TieSolid},
+									},
+									Duration: abc.NoteLength{Numerator: 1, Denominator: 1},
+								},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineSingle},
+							Notation: []abc.Notation{
+								abc.Chord{
+									Notes: []abc.Note{
+										{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+										{Pitch: 'E', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									},
+									Duration:   abc.NoteLength{Numerator: 1, Denominator: 1},
+									SlursEnded: []int{4, 1},
+								},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "attaches decorations to the following notation",
+			input: `X:1
+L:1/8
+K:C
+~A .TB !mordent!!p![CE] !fermata!z !segno!|{g}!emphasis!c !wedge!|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:   1,
+					NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+					Key:        abc.Key{Tonic: 'C'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'A', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Decorations: []abc.Decoration{abc.DecorationRoll}},
+								abc.Note{Pitch: 'B', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Decorations: []abc.Decoration{abc.DecorationStaccato, abc.DecorationTrill}, BeamBreak: true},
+								abc.Chord{
+									Notes: []abc.Note{
+										{Pitch: 'C', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+										{Pitch: 'E', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									},
+									Duration:    abc.NoteLength{Numerator: 1, Denominator: 1},
+									Decorations: []abc.Decoration{abc.DecorationLowerMordent, abc.DecorationP},
+									BeamBreak:   true,
+								},
+								abc.Rest{Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Decorations: []abc.Decoration{abc.DecorationFermata}, BeamBreak: true},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle, Decorations: []abc.Decoration{abc.DecorationSegno}},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineSingle, Decorations: []abc.Decoration{abc.DecorationSegno}},
+							Notation: []abc.Notation{
+								abc.GraceNotes{Notes: []abc.Note{
+									{Pitch: 'G', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								}},
+								abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Decorations: []abc.Decoration{abc.DecorationAccent}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle, Decorations: []abc.Decoration{abc.DecorationWedge}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "keeps quoted text that is not a chord",
+			input: `X:1
+L:1/8
+K:G
+"N.C."z "(G)"G "Fine"|]
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:   1,
+					NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+					Key:        abc.Key{Tonic: 'G'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Rest{
+									Duration:     abc.NoteLength{Numerator: 1, Denominator: 1},
+									ChordSymbols: []abc.ChordSymbol{{Text: "N.C."}},
+								},
+								abc.Note{
+									Pitch:        'G',
+									Duration:     abc.NoteLength{Numerator: 1, Denominator: 1},
+									ChordSymbols: []abc.ChordSymbol{{Text: "(G)"}},
+									BeamBreak:    true,
+								},
+							},
+							Right: abc.BarLine{
+								Type:         abc.BarLineThinThick,
+								ChordSymbols: []abc.ChordSymbol{{Text: "Fine"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "attaches chord symbols to the following notation",
+			input: `X:1
+L:1/8
+K:G
+"G"G2 "Em"z "Am7/G"!fermata![Ac]|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:   1,
+					NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+					Key:        abc.Key{Tonic: 'G'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Note{
+									Pitch:        'G',
+									Duration:     abc.NoteLength{Numerator: 2, Denominator: 1},
+									ChordSymbols: []abc.ChordSymbol{{Root: 'G'}},
+								},
+								abc.Rest{
+									Duration:     abc.NoteLength{Numerator: 1, Denominator: 1},
+									ChordSymbols: []abc.ChordSymbol{{Root: 'E', Quality: abc.ChordMinor}},
+									BeamBreak:    true,
+								},
+								abc.Chord{
+									Notes: []abc.Note{
+										{Pitch: 'A', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+										{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+									},
+									Duration:     abc.NoteLength{Numerator: 1, Denominator: 1},
+									ChordSymbols: []abc.ChordSymbol{{Root: 'A', Quality: abc.ChordMinorSeventh, Bass: 'G'}},
+									Decorations:  []abc.Decoration{abc.DecorationFermata},
+									BeamBreak:    true,
+								},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "attaches variant endings to bar lines",
+			input: `X:1
+K:D
+|:a b|1 c d:|2 e f|]
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:           1,
+					NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+					NoteLengthInferred: true,
+					Key:                abc.Key{Tonic: 'D'},
+					Bars: []abc.Bar{
+						{
+							Left: abc.BarLine{Type: abc.BarLineStartRepeat},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'A', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								abc.Note{Pitch: 'B', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle, Variants: []int{1}},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineSingle, Variants: []int{1}},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								abc.Note{Pitch: 'D', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+							},
+							Right: abc.BarLine{Type: abc.BarLineEndRepeat, Variants: []int{2}},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineEndRepeat, Variants: []int{2}},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'E', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+								abc.Note{Pitch: 'F', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, BeamBreak: true},
+							},
+							Right: abc.BarLine{Type: abc.BarLineThinThick},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "joins bar lines with no music between them",
+			input: `X:1
+K:D
+|:a:|
+|:b:|
+|c|
+|]
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:           1,
+					NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+					NoteLengthInferred: true,
+					Key:                abc.Key{Tonic: 'D'},
+					Bars: []abc.Bar{
+						{
+							Left: abc.BarLine{Type: abc.BarLineStartRepeat},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'A', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineStartEndRepeat},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineStartEndRepeat},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'B', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineEndRepeat},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineEndRepeat},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineThinThick},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "reads lists and ranges of variant endings",
+			input: `X:1
+K:D
+|:a|1,3 b:|2-3
+z:|
+`,
+			expected: []abc.Tune{
+				{
+					Sequence:           1,
+					NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+					NoteLengthInferred: true,
+					Key:                abc.Key{Tonic: 'D'},
+					Bars: []abc.Bar{
+						{
+							Left: abc.BarLine{Type: abc.BarLineStartRepeat},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'A', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle, Variants: []int{1, 3}},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineSingle, Variants: []int{1, 3}},
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'B', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineEndRepeat, Variants: []int{2, 3}},
+						},
+						{
+							Left: abc.BarLine{Type: abc.BarLineEndRepeat, Variants: []int{2, 3}},
+							Notation: []abc.Notation{
+								abc.Rest{Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineEndRepeat},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "separates tunes with blank lines",
+			input: `X:1
+T:First
+K:C
+C|
+
+Free text between tunes
+
+X:2
+T:Second
+K:C
+D|`,
+			expected: []abc.Tune{
+				{
+					Sequence:           1,
+					Title:              "First",
+					NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+					NoteLengthInferred: true,
+					Key:                abc.Key{Tonic: 'C'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'C', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+					},
+				},
+				{
+					Sequence:           2,
+					Title:              "Second",
+					NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+					NoteLengthInferred: true,
+					Key:                abc.Key{Tonic: 'C'},
+					Bars: []abc.Bar{
+						{
+							Notation: []abc.Notation{
+								abc.Note{Pitch: 'D', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}},
+							},
+							Right: abc.BarLine{Type: abc.BarLineSingle},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(test.input))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(test.expected, got) {
+				t.Errorf("tunes did not match: %v", cmp.Diff(test.expected, got))
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected ParseError
+	}{
+		{
+			name:  "unknown character",
+			input: "X:1\nK:G\nab|c$d\n",
+			expected: ParseError{
+				Line: 3, Column: 5, Offset: 12,
+				Msg: "unknown character: $",
+			},
+		},
+		{
+			name:  "invalid variant range",
+			input: "X:1\nK:G\na|3-1 b|\n",
+			expected: ParseError{
+				Line: 3, Column: 5, Offset: 12,
+				Token: "1",
+				Msg:   "invalid variant range 3-1",
+			},
+		},
+		{
+			name:  "variant ending out of range",
+			input: "X:1\nK:G\na|99999999999999999999 b|\n",
+			expected: ParseError{
+				Line: 3, Column: 3, Offset: 10,
+				Token: "99999999999999999999",
+				Msg:   "invalid variant ending 99999999999999999999",
+			},
+		},
+		{
+			name:  "field continuation",
+			input: "X:1\nT:Title\n+:continued\nK:G\n",
+			expected: ParseError{
+				Line: 3, Column: 1, Offset: 12,
+				Token: "+",
+				Msg:   "field continuation is not supported",
+			},
+		},
+		{
+			name:  "missing denominator",
+			input: "X:1\nM:4/\nK:G\n",
+			expected: ParseError{
+				Line: 2, Column: 3, Offset: 6,
+				Token: "4/",
+				Msg:   "missing denominator in meter",
+			},
+		},
+		{
+			name:  "invalid key",
+			input: "X:1\nK:Gfoo\n",
+			expected: ParseError{
+				Line: 2, Column: 3, Offset: 6,
+				Token: "Gfoo",
+				Msg:   `unknown mode "foo"`,
+			},
+		},
+		{
+			name:  "invalid parts",
+			input: "X:1\nP:A(B\nK:G\n",
+			expected: ParseError{
+				Line: 2, Column: 1, Offset: 4,
+				Token: "P",
+				Msg:   "P field: unterminated group in parts",
+			},
+		},
+		{
+			name:  "columns count characters",
+			input: "X:1\nK:G\n\"^é\"A/0\n",
+			expected: ParseError{
+				Line: 3, Column: 7, Offset: 15,
+				Token: "0",
+				Msg:   `invalid note length divisor "0"`,
+			},
+		},
+		{
+			name:  "microtone without fraction",
+			input: "X:1\nK:G\nA^3c\n",
+			expected: ParseError{
+				Line: 3, Column: 2, Offset: 9,
+				Token: "^",
+				Msg:   "invalid microtonal accidental",
+			},
+		},
+		{
+			name:  "broken rhythm without a note",
+			input: "X:1\nK:G\nz>A\n",
+			expected: ParseError{
+				Line: 3, Column: 2, Offset: 9,
+				Token: ">",
+				Msg:   "broken rhythm must follow a note or chord",
+			},
+		},
+		{
+			name:  "tuplet across a bar line",
+			input: "X:1\nK:G\n(3AB|c\n",
+			expected: ParseError{
+				Line: 3, Column: 5, Offset: 12,
+				Token: "|",
+				Msg:   "tuplet must end before the bar line",
+			},
+		},
+		{
+			name:  "sequence number in the body",
+			input: "X:1\nK:G\nA[X:2]B\n",
+			expected: ParseError{
+				Line: 3, Column: 3, Offset: 10,
+				Token: "X",
+				Msg:   "X field is not allowed in the tune body",
+			},
+		},
+		{
+			name:  "header field in the body",
+			input: "X:1\nK:G\nA|\nC:Composer\n",
+			expected: ParseError{
+				Line: 4, Column: 1, Offset: 11,
+				Token: "C",
+				Msg:   "C field is not allowed in the tune body",
+			},
+		},
+		{
+			name:  "grace notes before a rest",
+			input: "X:1\nK:G\nA{g}z\n",
+			expected: ParseError{
+				Line: 3, Column: 5, Offset: 12,
+				Token: "z",
+				Msg:   "grace notes must be followed by a note or chord",
+			},
+		},
+		{
+			name:  "unclosed slur",
+			input: "X:1\nK:G\nA(B(c)|d\n\nX:2\nK:G\nA\n",
+			expected: ParseError{
+				Line: 3, Column: 2, Offset: 9,
+				Token: "(",
+				Msg:   "unclosed slur",
+			},
+		},
+		{
+			name:  "chord symbol at the end of a tune",
+			input: "X:1\nK:G\nA|\"Am7\"\n",
+			expected: ParseError{
+				Line: 3, Column: 4, Offset: 11,
+				Token: "Am7",
+				Msg:   "chord symbol must be followed by a note, chord, rest or bar line",
+			},
+		},
+		{
+			name:  "chord symbol left in another voice",
+			input: "X:1\nK:G\nV:1\nA\"Am7\"[V:2]B|\n",
+			expected: ParseError{
+				Line: 4, Column: 3, Offset: 14,
+				Token: "Am7",
+				Msg:   "chord symbol must be followed by a note, chord, rest or bar line",
+			},
+		},
+		{
+			name:  "slur ended without being started",
+			input: "X:1\nK:G\nAB)\n",
+			expected: ParseError{
+				Line: 3, Column: 3, Offset: 10,
+				Token: ")",
+				Msg:   "slur ended without being started",
+			},
+		},
+		{
+			name:  "slur ended in another voice",
+			input: "X:1\nK:G\nV:1\n(A[V:2]B)|\n",
+			expected: ParseError{
+				Line: 4, Column: 9, Offset: 20,
+				Token: ")",
+				Msg:   "slur ended without being started",
+			},
+		},
+		{
+			name:  "unclosed slur in another voice",
+			input: "X:1\nK:G\nV:1\nA(B|[V:2](C)D|\n",
+			expected: ParseError{
+				Line: 4, Column: 2, Offset: 13,
+				Token: "(",
+				Msg:   "unclosed slur",
+			},
+		},
+		{
+			name:  "decoration before a multi-measure rest",
+			input: "X:1\nK:G\n!f!Z2|\n",
+			expected: ParseError{
+				Line: 3, Column: 4, Offset: 11,
+				Token: "Z",
+				Msg:   "decoration must be followed by a note, chord, rest or bar line",
+			},
+		},
+		{
+			name:  "unterminated decoration",
+			input: "X:1\nK:G\nA!trill\n",
+			expected: ParseError{
+				Line: 3, Column: 3, Offset: 10,
+				Msg: "unterminated decoration",
+			},
+		},
+		{
+			name:  "end of input",
+			input: "X:1\nM:4/4\nL:1",
+			expected: ParseError{
+				Line: 3, Column: 4, Offset: 13,
+				Expected: []string{"/"},
+				Msg:      "unexpected EOF, expected /",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(test.input))
+			var got *ParseError
+			if !errors.As(err, &got) {
+				t.Fatalf("expected a ParseError, got %v", err)
+			}
+			if !cmp.Equal(test.expected, *got) {
+				t.Errorf("errors did not match: %v", cmp.Diff(test.expected, *got))
+			}
+		})
+	}
+}
+
+func TestParseErrorString(t *testing.T) {
+	err := &ParseError{File: "tunes.abc", Line: 3, Column: 5, Msg: "unknown character: $"}
+	if got, want := err.Error(), "tunes.abc:3:5: unknown character: $"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	err.File = ""
+	if got, want := err.Error(), "3:5: unknown character: $"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadTolerant(t *testing.T) {
+	input := `X:1
+T:First
+M:4/
+K:C
+A$B|c|
+D|
+
+X:bad
+T:Skipped
+K:C
+E|
+
+X:3
+T:Third
+K:C
+(F|
+`
+	tunes, diagnostics, err := ReadTolerant(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	note := func(pitch abc.Pitch) abc.Note {
+		return abc.Note{Pitch: pitch, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}}
+	}
+	slurred := func(pitch abc.Pitch) abc.Note {
+		n := note(pitch)
+		n.Slurs = []abc.Slur{{ID: 1}}
+		return n
+	}
+	expectedTunes := []abc.Tune{
+		{
+			Sequence:           1,
+			Title:              "First",
+			NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+			NoteLengthInferred: true,
+			Key:                abc.Key{Tonic: 'C'},
+			Bars: []abc.Bar{
+				// The rest of the line after the error is skipped
+				{Notation: []abc.Notation{note('A'), note('D')}, Right: abc.BarLine{Type: abc.BarLineSingle}},
+			},
+		},
+		{
+			Sequence:           3,
+			Title:              "Third",
+			NoteLength:         abc.NoteLength{Numerator: 1, Denominator: 8},
+			NoteLengthInferred: true,
+			Key:                abc.Key{Tonic: 'C'},
+			Bars: []abc.Bar{
+				// The tune is kept despite its unclosed slur
+				{Notation: []abc.Notation{slurred('F')}, Right: abc.BarLine{Type: abc.BarLineSingle}},
+			},
+		},
+	}
+	if !cmp.Equal(expectedTunes, tunes) {
+		t.Errorf("tunes did not match: %v", cmp.Diff(expectedTunes, tunes))
+	}
+
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, fmt.Sprintf("%d %v", d.Sequence, d))
+	}
+	expectedDiagnostics := []string{
+		"1 3:3: missing denominator in meter",
+		"1 5:2: unknown character: $",
+		"0 8:3: expected number",
+		"3 16:1: unclosed slur",
+	}
+	if !cmp.Equal(expectedDiagnostics, got) {
+		t.Errorf("diagnostics did not match: %v", cmp.Diff(expectedDiagnostics, got))
+	}
+}
+
+func TestParseStopsLexer(t *testing.T) {
+	lexer := &itemSource{
+		items: []*item{
+			i(itemFieldName, "X"), i(itemNumber, "1"), newline(),
+			i(itemFieldName, "K"), i(itemKey, "G"), newline(),
+			i(itemLetter, "A"), i(itemError, "unknown character: $"),
+			i(itemLetter, "B"), newline(),
+		},
+	}
+	p := parser{
+		ctx:   context.Background(),
+		lexer: lexer,
+	}
+	if _, err := p.parse(); err == nil {
+		t.Fatal("expected an error")
+	}
+	// No items are read after the error
+	if lexer.pos != 8 {
+		t.Errorf("expected 8 items to be read, got %d", lexer.pos)
+	}
+	if !lexer.stopped {
+		t.Error("expected the lexer to be stopped")
+	}
+}
+
+func TestLexStopsWhenDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := lex(ctx, "", "X:1\nK:G\nABc|\nd|\n")
+	if l.nextItem() == nil {
+		t.Fatal("expected an item before the context is done")
+	}
+	cancel()
+
+	// Items already scanned may still be returned, but no more are scanned
+	for scanned := len(l.items) - l.head; scanned > 0; scanned-- {
+		l.nextItem()
+	}
+	if item := l.nextItem(); item != nil {
+		t.Errorf("expected no more items, got %v", item)
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tunes, err := ReadContext(ctx, strings.NewReader("X:1\nK:G\nABc|\n"))
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if tunes != nil {
+		t.Errorf("expected no tunes, got %v", tunes)
+	}
+}
+
+// benchmarkTune is a typical tune, repeated to build a large tune book.
+const benchmarkTune = `X:%d
+T:The Kesh
+R:jig
+M:6/8
+L:1/8
+Q:3/8=120
+K:G
+|:GAG GAB|ABA ABd|edd gdd|edB dBA|
+GAG GAB|ABA ABd|edd gdB|1 AGF G2 D:|2 AGF G3|
+|:[DG]2 g fdB|dBA ABd|edd gdd|edB dBA|
+w: Some words to go with the notes of the tune * here
+gfg aga|bag age|edd gdB|AGF G3:|
+
+`
+
+// tuneBook returns the text of a tune book containing n tunes.
+func tuneBook(n int) string {
+	var book strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&book, benchmarkTune, i)
+	}
+	return book.String()
+}
+
+func BenchmarkRead(b *testing.B) {
+	book := tuneBook(1000)
+	b.SetBytes(int64(len(book)))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := Read(strings.NewReader(book)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDefaultNoteLength(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected abc.NoteLength
+		inferred bool
+		beat     []abc.NoteLength
+	}{
+		{
+			name:     "meter below 3/4",
+			input:    "X:1\nM:2/4\nQ:120\nK:C\n",
+			expected: abc.NoteLength{Numerator: 1, Denominator: 16},
+			inferred: true,
+			beat:     []abc.NoteLength{{Numerator: 1, Denominator: 16}},
+		},
+		{
+			name:     "meter of 3/4",
+			input:    "X:1\nM:3/4\nK:C\n",
+			expected: abc.NoteLength{Numerator: 1, Denominator: 8},
+			inferred: true,
+		},
+		{
+			name:     "complex meter",
+			input:    "X:1\nM:2+3/8\nK:C\n",
+			expected: abc.NoteLength{Numerator: 1, Denominator: 16},
+			inferred: true,
+		},
+		{
+			name:     "no meter",
+			input:    "X:1\nK:C\n",
+			expected: abc.NoteLength{Numerator: 1, Denominator: 8},
+			inferred: true,
+		},
+		{
+			name:     "explicit",
+			input:    "X:1\nM:2/4\nL:1/4\nK:C\n",
+			expected: abc.NoteLength{Numerator: 1, Denominator: 4},
+		},
+		{
+			name:     "changed in the body",
+			input:    "X:1\nM:2/4\nQ:120\nK:C\nA[L:1/4]B|\nL:1/2\nc|\n",
+			expected: abc.NoteLength{Numerator: 1, Denominator: 16},
+			inferred: true,
+			beat:     []abc.NoteLength{{Numerator: 1, Denominator: 16}},
+		},
+		{
+			name:     "from file header",
+			input:    "L:1/4\n\nX:1\nM:2/4\nK:C\n",
+			expected: abc.NoteLength{Numerator: 1, Denominator: 4},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tunes, err := Read(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := tunes[0]
+			if !cmp.Equal(test.expected, got.NoteLength) {
+				t.Errorf("note length did not match: %v", cmp.Diff(test.expected, got.NoteLength))
+			}
+			if got.NoteLengthInferred != test.inferred {
+				t.Errorf("expected inferred to be %v, got %v", test.inferred, got.NoteLengthInferred)
+			}
+			if !cmp.Equal(test.beat, got.Tempo.Beat) {
+				t.Errorf("tempo beat did not match: %v", cmp.Diff(test.beat, got.Tempo.Beat))
+			}
+		})
+	}
+}
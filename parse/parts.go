@@ -0,0 +1,64 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// parsePlayOrder parses the value of a P: field in a tune header, such as
+// A2B(AC)2 or ((AB)3.(CD)2).
+func parsePlayOrder(value string) (abc.PlayOrder, error) {
+	order, rest, err := parsePlayOrderGroup(value)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q in parts", rest)
+	}
+	return order, nil
+}
+
+// parsePlayOrderGroup parses parts up to the end of value or a closing
+// parenthesis, returning any remaining input.
+func parsePlayOrderGroup(value string) (abc.PlayOrder, string, error) {
+	var order abc.PlayOrder
+	for value != "" {
+		c := value[0]
+		value = value[1:]
+
+		var item abc.PlayOrderItem
+		switch {
+		case c == ' ' || c == '\t' || c == '.':
+			// Spaces and dots only aid readability
+			continue
+		case c == ')':
+			return order, ")" + value, nil
+		case c == '(':
+			var err error
+			item.Group, value, err = parsePlayOrderGroup(value)
+			if err != nil {
+				return nil, value, err
+			}
+			if !strings.HasPrefix(value, ")") {
+				return nil, value, fmt.Errorf("unterminated group in parts")
+			}
+			value = value[1:]
+		case c >= 'A' && c <= 'Z':
+			item.Part = string(c)
+		default:
+			return nil, value, fmt.Errorf("unexpected %q in parts", c)
+		}
+
+		digits := len(value) - len(strings.TrimLeft(value, "0123456789"))
+		item.Repeat = 1
+		if digits > 0 {
+			item.Repeat, _ = strconv.Atoi(value[:digits])
+			value = value[digits:]
+		}
+		order = append(order, item)
+	}
+	return order, value, nil
+}
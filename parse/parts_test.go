@@ -0,0 +1,113 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+func TestParsePlayOrder(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected abc.PlayOrder
+		expanded []string
+	}{
+		{
+			input: "ABAC",
+			expected: abc.PlayOrder{
+				{Part: "A", Repeat: 1},
+				{Part: "B", Repeat: 1},
+				{Part: "A", Repeat: 1},
+				{Part: "C", Repeat: 1},
+			},
+			expanded: []string{"A", "B", "A", "C"},
+		},
+		{
+			input: "A2B(AC)2",
+			expected: abc.PlayOrder{
+				{Part: "A", Repeat: 2},
+				{Part: "B", Repeat: 1},
+				{
+					Group: abc.PlayOrder{
+						{Part: "A", Repeat: 1},
+						{Part: "C", Repeat: 1},
+					},
+					Repeat: 2,
+				},
+			},
+			expanded: []string{"A", "A", "B", "A", "C", "A", "C"},
+		},
+		{
+			input: "((AB)3.(CD)2)",
+			expected: abc.PlayOrder{
+				{
+					Group: abc.PlayOrder{
+						{
+							Group: abc.PlayOrder{
+								{Part: "A", Repeat: 1},
+								{Part: "B", Repeat: 1},
+							},
+							Repeat: 3,
+						},
+						{
+							Group: abc.PlayOrder{
+								{Part: "C", Repeat: 1},
+								{Part: "D", Repeat: 1},
+							},
+							Repeat: 2,
+						},
+					},
+					Repeat: 1,
+				},
+			},
+			expanded: []string{"A", "B", "A", "B", "A", "B", "C", "D", "C", "D"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := parsePlayOrder(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(test.expected, got) {
+				t.Errorf("play orders did not match: %v", cmp.Diff(test.expected, got))
+			}
+			if expanded := got.Expand(); !cmp.Equal(test.expanded, expanded) {
+				t.Errorf("expanded parts did not match: %v", cmp.Diff(test.expanded, expanded))
+			}
+		})
+	}
+}
+
+func TestParsePlayOrderErrors(t *testing.T) {
+	for _, input := range []string{"A(B", "AB)", "A-B"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parsePlayOrder(input); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadBodyParts(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+P:AB
+K:G
+P:A
+G|
+P:B
+B|
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var parts []string
+	for _, bar := range tunes[0].Bars {
+		parts = append(parts, bar.Part)
+	}
+	if expected := []string{"A", "B"}; !cmp.Equal(expected, parts) {
+		t.Errorf("parts did not match: %v", cmp.Diff(expected, parts))
+	}
+}
@@ -0,0 +1,85 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// parseTempo parses the value of a Q: field, such as 1/4=120,
+// "Allegro" 1/4=120 or 1/4 3/8 1/4=40. In the deprecated form, a bare
+// number such as 120, the returned tempo has no beat; the caller is
+// responsible for applying the unit note length.
+func parseTempo(value string) (abc.Tempo, error) {
+	tempo := abc.Tempo{}
+	var text []string
+	rest := strings.TrimSpace(value)
+	for rest != "" {
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return tempo, fmt.Errorf("unterminated text in tempo %q", value)
+			}
+			text = append(text, rest[1:end+1])
+			rest = strings.TrimSpace(rest[end+2:])
+			continue
+		}
+
+		var field string
+		if end := strings.IndexAny(rest, " \t\""); end >= 0 {
+			field, rest = rest[:end], strings.TrimSpace(rest[end:])
+		} else {
+			field, rest = rest, ""
+		}
+
+		beat, bpm, hasBPM := strings.Cut(field, "=")
+		if beat != "" {
+			if !strings.Contains(beat, "/") && !hasBPM {
+				// Deprecated form, giving only the beats per minute
+				bpm, hasBPM = beat, true
+			} else {
+				length, err := parseBeatFraction(beat)
+				if err != nil {
+					return tempo, fmt.Errorf("invalid beat %q in tempo", beat)
+				}
+				tempo.Beat = append(tempo.Beat, length)
+			}
+		}
+		if hasBPM && bpm == "" {
+			// Allow for spaces around the =
+			bpm, rest, _ = strings.Cut(rest, " ")
+			rest = strings.TrimSpace(rest)
+		}
+		if hasBPM {
+			var err error
+			tempo.BPM, err = strconv.Atoi(bpm)
+			if err != nil {
+				return tempo, fmt.Errorf("invalid beats per minute %q in tempo", bpm)
+			}
+		}
+	}
+	tempo.Text = strings.Join(text, " ")
+	return tempo, nil
+}
+
+// parseBeatFraction parses a beat of a tempo, a fraction of the form 3/8.
+func parseBeatFraction(value string) (abc.NoteLength, error) {
+	length := abc.NoteLength{}
+	numerator, denominator, ok := strings.Cut(value, "/")
+	if !ok {
+		return length, fmt.Errorf("expected a fraction, got %q", value)
+	}
+	var err error
+	if length.Numerator, err = strconv.Atoi(numerator); err != nil {
+		return length, err
+	}
+	if length.Denominator, err = strconv.Atoi(denominator); err != nil {
+		return length, err
+	}
+	if length.Denominator == 0 {
+		return length, fmt.Errorf("invalid fraction %q", value)
+	}
+	return length, nil
+}
@@ -0,0 +1,138 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+func TestParseTempo(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected abc.Tempo
+	}{
+		{
+			input: "1/4=120",
+			expected: abc.Tempo{
+				Beat: []abc.NoteLength{{Numerator: 1, Denominator: 4}},
+				BPM:  120,
+			},
+		},
+		{
+			input: "1/4 3/8 1/4=40",
+			expected: abc.Tempo{
+				Beat: []abc.NoteLength{
+					{Numerator: 1, Denominator: 4},
+					{Numerator: 3, Denominator: 8},
+					{Numerator: 1, Denominator: 4},
+				},
+				BPM: 40,
+			},
+		},
+		{
+			input: `"Allegro" 1/4=120`,
+			expected: abc.Tempo{
+				Beat: []abc.NoteLength{{Numerator: 1, Denominator: 4}},
+				BPM:  120,
+				Text: "Allegro",
+			},
+		},
+		{
+			input: `3/8 = 50 "Slowly"`,
+			expected: abc.Tempo{
+				Beat: []abc.NoteLength{{Numerator: 3, Denominator: 8}},
+				BPM:  50,
+				Text: "Slowly",
+			},
+		},
+		{
+			input:    `"Andante"`,
+			expected: abc.Tempo{Text: "Andante"},
+		},
+		{
+			input:    "120",
+			expected: abc.Tempo{BPM: 120},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := parseTempo(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(test.expected, got) {
+				t.Errorf("tempos did not match: %v", cmp.Diff(test.expected, got))
+			}
+		})
+	}
+}
+
+func TestReadDeprecatedTempo(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+Q:120
+L:1/4
+K:C
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := abc.Tempo{
+		Beat: []abc.NoteLength{{Numerator: 1, Denominator: 4}},
+		BPM:  120,
+	}
+	if got := tunes[0].Tempo; !cmp.Equal(expected, got) {
+		t.Errorf("tempos did not match: %v", cmp.Diff(expected, got))
+	}
+}
+
+func TestReadTempoChanges(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+Q:1/4=120
+L:1/8
+K:C
+A[Q:"Slower" 1/4=90]B|
+L:1/16
+Q:60
+c|
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := tunes[0]
+
+	// Changes of tempo in the body leave the header as it was given
+	expectedTempo := abc.Tempo{Beat: []abc.NoteLength{{Numerator: 1, Denominator: 4}}, BPM: 120}
+	if !cmp.Equal(expectedTempo, got.Tempo) {
+		t.Errorf("tempo did not match: %v", cmp.Diff(expectedTempo, got.Tempo))
+	}
+
+	// A tempo with no beat takes the unit note length in effect
+	note := func(pitch abc.Pitch, octave int) abc.Note {
+		return abc.Note{Pitch: pitch, Octave: octave, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}}
+	}
+	single := abc.BarLine{Type: abc.BarLineSingle}
+	expected := []abc.Bar{
+		{
+			Notation: []abc.Notation{
+				note('A', 0),
+				abc.TempoChange{Tempo: abc.Tempo{Beat: []abc.NoteLength{{Numerator: 1, Denominator: 4}}, BPM: 90, Text: "Slower"}},
+				note('B', 0),
+			},
+			Right: single,
+		},
+		{
+			Left: single,
+			Notation: []abc.Notation{
+				abc.NoteLengthChange{NoteLength: abc.NoteLength{Numerator: 1, Denominator: 16}},
+				abc.TempoChange{Tempo: abc.Tempo{Beat: []abc.NoteLength{{Numerator: 1, Denominator: 16}}, BPM: 60}},
+				note('C', 1),
+			},
+			Right: single,
+		},
+	}
+	if !cmp.Equal(expected, got.Bars) {
+		t.Errorf("bars did not match: %v", cmp.Diff(expected, got.Bars))
+	}
+}
@@ -0,0 +1,190 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// setVoice handles a V: field. In the header, this defines a voice. In the
+// body, it also directs the music that follows to that voice.
+func (p *parser) setVoice() error {
+	value, err := p.expectString()
+	if err != nil {
+		return err
+	}
+	fields, err := splitFields(value)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("missing voice ID")
+	}
+
+	id := fields[0]
+	voice := p.findVoice(id)
+	if voice == nil {
+		p.currentTune.Voices = append(p.currentTune.Voices, abc.Voice{ID: id})
+		voice = &p.currentTune.Voices[len(p.currentTune.Voices)-1]
+	}
+	if err := parseVoiceProperties(voice, fields[1:]); err != nil {
+		return err
+	}
+	if p.inBody {
+		p.switchVoice(id)
+	}
+	return nil
+}
+
+// parseVoiceProperties applies the properties given after the ID of a V:
+// field, such as name="Tenor" or clef=bass.
+func parseVoiceProperties(voice *abc.Voice, fields []string) error {
+	for _, field := range fields {
+		if isClefField(field) {
+			if err := parseClefField(&voice.Clef, field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, value, _ := strings.Cut(field, "=")
+		value = strings.Trim(value, `"`)
+		switch name {
+		case "name", "nm":
+			voice.Name = value
+		case "subname", "sname", "snm":
+			voice.Subname = value
+		case "stem":
+			switch value {
+			case "up":
+				voice.Stem = abc.StemUp
+			case "down":
+				voice.Stem = abc.StemDown
+			case "auto":
+				voice.Stem = abc.StemAuto
+			default:
+				return fmt.Errorf("invalid stem direction %q", value)
+			}
+		default:
+			// Other properties only affect layout
+		}
+	}
+	return nil
+}
+
+// findVoice returns the voice of the current tune with the given ID, or nil
+// if there is none. The result is only valid until another voice is added.
+func (p *parser) findVoice(id string) *abc.Voice {
+	if id == "" {
+		return nil
+	}
+	for i := range p.currentTune.Voices {
+		if p.currentTune.Voices[i].ID == id {
+			return &p.currentTune.Voices[i]
+		}
+	}
+	return nil
+}
+
+// bars returns the bars of the voice currently receiving music.
+func (p *parser) bars() *[]abc.Bar {
+	if voice := p.findVoice(p.voiceID); voice != nil {
+		return &voice.Bars
+	}
+	return &p.currentTune.Bars
+}
+
+// voiceState is the music of a voice that is set aside while another
+// voice receives music.
+type voiceState struct {
+	bar          abc.Bar           // incomplete bar
+	meter        abc.Meter         // meter in effect
+	noteLength   abc.NoteLength    // unit note length in effect
+	tuplets      []abc.Tuplet      // tuplets awaiting notes, innermost last
+	graceNotes   bool              // true if grace notes await the note they precede
+	decorations  []abc.Decoration  // decorations awaiting the notation they apply to
+	chordSymbols []abc.ChordSymbol // chord symbols awaiting the notation they apply to
+	chordStart   item              // the first of chordSymbols, for error reports
+	pendingSlurs []abc.Slur        // slurs awaiting the note or chord they start at
+	openSlurs    []openSlur        // slurs started but not yet ended, innermost last
+}
+
+// saveVoice returns the state of the voice receiving music.
+func (p *parser) saveVoice() voiceState {
+	return voiceState{
+		bar:          p.currentBar,
+		meter:        p.meter,
+		noteLength:   p.noteLength,
+		tuplets:      p.tuplets,
+		graceNotes:   p.graceNotes,
+		decorations:  p.decorations,
+		chordSymbols: p.chordSymbols,
+		chordStart:   p.chordStart,
+		pendingSlurs: p.pendingSlurs,
+		openSlurs:    p.openSlurs,
+	}
+}
+
+// restoreVoice resumes a voice from the state it was set aside in.
+func (p *parser) restoreVoice(state voiceState) {
+	p.currentBar = state.bar
+	p.meter, p.noteLength = state.meter, state.noteLength
+	p.tuplets, p.graceNotes = state.tuplets, state.graceNotes
+	p.decorations = state.decorations
+	p.chordSymbols, p.chordStart = state.chordSymbols, state.chordStart
+	p.pendingSlurs, p.openSlurs = state.pendingSlurs, state.openSlurs
+}
+
+// switchVoice directs the music that follows to the voice with the given
+// ID, setting aside the current voice along with any bar, tuplet, grace
+// notes, decorations, chord symbols or slurs it has not completed. A
+// broken rhythm awaiting its second note does not carry into the new
+// voice.
+func (p *parser) switchVoice(id string) {
+	if id == p.voiceID {
+		return
+	}
+	p.broken = 0
+	p.beamBreak = false
+	if p.pendingVoices == nil {
+		p.pendingVoices = make(map[string]voiceState)
+	}
+	p.pendingVoices[p.voiceID] = p.saveVoice()
+	state, ok := p.pendingVoices[id]
+	if !ok {
+		// A voice starts in the meter and unit note length of the tune
+		state = voiceState{meter: p.currentTune.Meter, noteLength: p.currentTune.NoteLength}
+	}
+	p.restoreVoice(state)
+	delete(p.pendingVoices, id)
+	p.voiceID = id
+}
+
+// splitFields splits value around spaces, except those within quotes.
+func splitFields(value string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	quoted := false
+	for _, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			field.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %q", value)
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
@@ -0,0 +1,121 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+)
+
+func TestReadVoices(t *testing.T) {
+	tunes, err := Read(strings.NewReader(`X:1
+V:S name="Soprano" sname=S stem=up
+V:B clef=bass middle=D,
+K:C
+V:S
+c2 d2|e4|
+V:B
+C2 D2|[V:S]g2 [V:B]E2|
+[V:S]c2|
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	note := func(pitch abc.Pitch, octave int, length int) abc.Note {
+		return abc.Note{Pitch: pitch, Octave: octave, Duration: abc.NoteLength{Numerator: length, Denominator: 1}}
+	}
+	spaced := func(n abc.Note) abc.Note {
+		n.BeamBreak = true
+		return n
+	}
+	single := abc.BarLine{Type: abc.BarLineSingle}
+	expected := []abc.Voice{
+		{
+			ID:      "S",
+			Name:    "Soprano",
+			Subname: "S",
+			Stem:    abc.StemUp,
+			Bars: []abc.Bar{
+				{Notation: []abc.Notation{note('C', 1, 2), spaced(note('D', 1, 2))}, Right: single},
+				{Left: single, Notation: []abc.Notation{note('E', 1, 4)}, Right: single},
+				{Left: single, Notation: []abc.Notation{note('G', 1, 2), note('C', 1, 2)}, Right: single},
+			},
+		},
+		{
+			ID:   "B",
+			Clef: abc.Clef{Type: abc.ClefBass, Middle: abc.StaffPitch{Pitch: 'D', Octave: -1}},
+			Bars: []abc.Bar{
+				{Notation: []abc.Notation{note('C', 0, 2), spaced(note('D', 0, 2))}, Right: single},
+				{Left: single, Notation: []abc.Notation{note('E', 0, 2)}, Right: single},
+			},
+		},
+	}
+	if got := tunes[0].Voices; !cmp.Equal(expected, got) {
+		t.Errorf("voices did not match: %v", cmp.Diff(expected, got))
+	}
+	if len(tunes[0].Bars) != 0 {
+		t.Errorf("expected no bars outside voices, got %v", tunes[0].Bars)
+	}
+}
+
+func TestReadBrokenRhythmAcrossVoices(t *testing.T) {
+	tunes, err := Read(strings.NewReader("X:1\nK:C\nV:1\nc>[V:2]d|\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The broken rhythm does not lengthen the note in the other voice
+	expected := []abc.Voice{
+		{
+			ID: "1",
+			Bars: []abc.Bar{
+				{Notation: []abc.Notation{abc.Note{Pitch: 'C', Octave: 1, Duration: abc.NoteLength{Numerator: 3, Denominator: 2}, Broken: 1}}},
+			},
+		},
+		{
+			ID: "2",
+			Bars: []abc.Bar{
+				{Notation: []abc.Notation{abc.Note{Pitch: 'D', Octave: 1, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}}}, Right: abc.BarLine{Type: abc.BarLineSingle}},
+			},
+		},
+	}
+	if got := tunes[0].Voices; !cmp.Equal(expected, got) {
+		t.Errorf("voices did not match: %v", cmp.Diff(expected, got))
+	}
+}
+
+func TestReadGroupsAcrossVoices(t *testing.T) {
+	tunes, err := Read(strings.NewReader("X:1\nK:C\nV:1\n(3AB[V:2]!f!\"G\"[V:1]c|[V:2]d|\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A tuplet, decoration or chord symbol waits for the next notation of
+	// its own voice
+	note := func(pitch abc.Pitch, octave int) abc.Note {
+		return abc.Note{Pitch: pitch, Octave: octave, Duration: abc.NoteLength{Numerator: 1, Denominator: 1}}
+	}
+	decorated := note('D', 1)
+	decorated.Decorations = []abc.Decoration{"f"}
+	decorated.ChordSymbols = []abc.ChordSymbol{{Root: 'G'}}
+	single := abc.BarLine{Type: abc.BarLineSingle}
+	expected := []abc.Voice{
+		{
+			ID: "1",
+			Bars: []abc.Bar{
+				{Notation: []abc.Notation{abc.Tuplet{P: 3, Q: 2, R: 3, Notation: []abc.Notation{note('A', 0), note('B', 0), note('C', 1)}}}, Right: single},
+			},
+		},
+		{
+			ID: "2",
+			Bars: []abc.Bar{
+				{Notation: []abc.Notation{decorated}, Right: single},
+			},
+		},
+	}
+	if got := tunes[0].Voices; !cmp.Equal(expected, got) {
+		t.Errorf("voices did not match: %v", cmp.Diff(expected, got))
+	}
+}
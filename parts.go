@@ -0,0 +1,29 @@
+package abc
+
+// PlayOrder is the order in which the parts of a tune are played, as given
+// by the P: field in the tune header.
+type PlayOrder []PlayOrderItem
+
+// PlayOrderItem is a single part or a group of parts, played one or more
+// times in succession.
+type PlayOrderItem struct {
+	Part   string    // Label of the part, if this item is not a group
+	Group  PlayOrder // Parts within a parenthesized group
+	Repeat int       // Number of times the part or group is played
+}
+
+// Expand returns the labels of each part in the order they are played,
+// with all repeats and groups written out in full.
+func (p PlayOrder) Expand() []string {
+	var parts []string
+	for _, item := range p {
+		for i := 0; i < item.Repeat; i++ {
+			if item.Group != nil {
+				parts = append(parts, item.Group.Expand()...)
+			} else {
+				parts = append(parts, item.Part)
+			}
+		}
+	}
+	return parts
+}
@@ -0,0 +1,19 @@
+package abc
+
+// Tempo is the speed of a tune, as given by the Q: field.
+type Tempo struct {
+	Beat []NoteLength // Note lengths making up one beat, added together
+	BPM  int          // Beats per minute
+	Text string       // Descriptive text, such as "Allegro"
+}
+
+// TempoChange is a change of tempo within the body of a tune, given by a
+// Q: field between lines of music or inline as [Q:1/4=90].
+type TempoChange struct {
+	Tempo Tempo
+}
+
+// Length returns zero, since a change of tempo takes no time.
+func (t TempoChange) Length() Duration {
+	return Duration{}.Normalize()
+}
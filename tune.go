@@ -1,50 +1,94 @@
 package abc
 
 type Tune struct {
-	Area           string // deprecated
-	Book           string
-	Composer       string
-	Discography    string
-	FileURL        string
-	History        []string
-	Group          string
-	Sequence       int
-	Title          string
-	Comments       []string
-	Rhythm         string
-	Origin         string
-	Meter          Meter
-	NoteLength     NoteLength
-	Key            Key
-	Source         string
-	Transcription  string
-	WordsAfterTune []string
-
-	Bars []Bar
+	Area        string // deprecated
+	Book        string
+	Composer    string
+	Discography string
+	FileURL     string
+	History     []string
+	Group       string
+	Sequence    int
+	Title       string
+	Comments    []string
+	Rhythm      string
+	Origin      string
+	Meter       Meter
+	NoteLength  NoteLength
+	// NoteLengthInferred is true if the tune has no L: field, so the
+	// NoteLength was derived from the Meter.
+	NoteLengthInferred bool
+	Key                Key
+	Clef               Clef
+	Tempo              Tempo
+	Parts              PlayOrder
+	Source             string
+	Transcription      string
+	Directives         []Directive
+	UserSymbols        []UserSymbol
+	WordsAfterTune     []string
+
+	// Bars holds the music of a tune without voices, along with any music
+	// preceding the first voice of a tune with them.
+	Bars   []Bar
+	Voices []Voice
 }
 
+// Bar is a single measure of music. Adjacent bars share the bar line
+// between them, so the Right of one bar is the Left of the next.
 type Bar struct {
-	Left     BarLine
-	Notation []Notation
-	Right    BarLine
+	Part       string      // Label of the part beginning with this bar, if any
+	Title      string      // Title of the section beginning with this bar, given by T: in the body
+	Directives []Directive // Directives given in the music before or within this bar
+	Left       BarLine
+	Notation   []Notation
+	Right      BarLine
 }
 
+// BarLine is a bar line along with any variant endings that begin at it.
+// Chord symbols before a bar line, such as "Fine"|], are kept with it.
 type BarLine struct {
-	Repeat int
+	Type         BarLineType
+	Variants     []int
+	ChordSymbols []ChordSymbol
+	Decorations  []Decoration
 }
 
+// BarLineType identifies the style of a bar line.
+type BarLineType int
+
+const (
+	BarLineNone           BarLineType = iota
+	BarLineSingle                     // |
+	BarLineDotted                     // .|
+	BarLineThinThin                   // ||
+	BarLineThinThick                  // |]
+	BarLineThickThin                  // [|
+	BarLineStartRepeat                // |:
+	BarLineEndRepeat                  // :|
+	BarLineStartEndRepeat             // ::
+)
+
+// Notation is an element of music within a bar, such as a note or rest.
 type Notation interface {
-	Length() int
+	// Length returns the length of this element as a multiple of the unit
+	// note length, in lowest terms.
+	Length() Duration
 }
 
-type Key string
+// NoteLength is the length of a note, either as a fraction of a whole note
+// or as a multiple of the unit note length.
+type NoteLength = Duration
 
-type Meter struct {
-	Numerator   []int
-	Denominator int
+// NoteLengthChange is a change of the unit note length within the body of
+// a tune, given by an L: field between lines of music or inline as
+// [L:1/16]. The lengths of the notes that follow are multiples of the new
+// unit note length.
+type NoteLengthChange struct {
+	NoteLength NoteLength
 }
 
-type NoteLength struct {
-	Numerator   int
-	Denominator int
+// Length returns zero, since a change of unit note length takes no time.
+func (l NoteLengthChange) Length() Duration {
+	return Duration{}.Normalize()
 }
@@ -0,0 +1,21 @@
+package abc
+
+// Voice is a single part of a tune with multiple voices, as introduced by
+// the V: field.
+type Voice struct {
+	ID      string
+	Name    string // Name printed at the start of the first staff
+	Subname string // Name printed at the start of subsequent staves
+	Clef    Clef
+	Stem    Stem
+	Bars    []Bar
+}
+
+// Stem is the direction of note stems within a voice.
+type Stem int
+
+const (
+	StemAuto Stem = iota
+	StemUp
+	StemDown
+)
@@ -0,0 +1,747 @@
+// Package write serializes abc.Tune values as ABC 2.1 text.
+package write
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/theothertomelliott/abc"
+)
+
+// barsPerLine is the number of bars written on each line of music.
+const barsPerLine = 4
+
+// Write serializes a sequence of tunes to an output stream, separated by
+// blank lines.
+func Write(out io.Writer, tunes []abc.Tune) error {
+	w := bufio.NewWriter(out)
+	for i, tune := range tunes {
+		if i > 0 {
+			w.WriteString("\n")
+		}
+		writeTune(w, tune)
+	}
+	return w.Flush()
+}
+
+func writeTune(w *bufio.Writer, tune abc.Tune) {
+	writeField(w, 'X', strconv.Itoa(tune.Sequence))
+	writeField(w, 'T', tune.Title)
+	writeField(w, 'C', tune.Composer)
+	writeField(w, 'O', tune.Origin)
+	writeField(w, 'A', tune.Area)
+	writeField(w, 'R', tune.Rhythm)
+	writeField(w, 'M', formatMeter(tune.Meter))
+	if tune.NoteLength.Denominator != 0 && !tune.NoteLengthInferred {
+		writeField(w, 'L', formatFraction(tune.NoteLength))
+	}
+	writeField(w, 'Q', formatTempo(tune.Tempo))
+	writeField(w, 'P', formatPlayOrder(tune.Parts))
+	for _, voice := range tune.Voices {
+		writeField(w, 'V', formatVoice(voice))
+	}
+	writeField(w, 'B', tune.Book)
+	writeField(w, 'D', tune.Discography)
+	writeField(w, 'F', tune.FileURL)
+	writeField(w, 'G', tune.Group)
+	for _, history := range tune.History {
+		writeField(w, 'H', history)
+	}
+	for _, comment := range tune.Comments {
+		writeField(w, 'N', comment)
+	}
+	writeField(w, 'S', tune.Source)
+	writeField(w, 'Z', tune.Transcription)
+	for _, directive := range tune.Directives {
+		writeDirective(w, directive)
+	}
+	for _, symbol := range tune.UserSymbols {
+		writeField(w, 'U', formatUserSymbol(symbol))
+	}
+	writeField(w, 'K', formatKey(tune.Key, tune.Clef))
+
+	writeBars(w, tune.Meter, tune.Bars)
+	for _, voice := range tune.Voices {
+		writeField(w, 'V', voice.ID)
+		writeBars(w, tune.Meter, voice.Bars)
+	}
+
+	for _, words := range tune.WordsAfterTune {
+		writeField(w, 'W', words)
+	}
+}
+
+// writeField writes an information field on its own line, unless its
+// value is empty.
+func writeField(w *bufio.Writer, name byte, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(w, "%c:%s\n", name, value)
+}
+
+// writeDirective writes a directive as an I: field or a %% line, in the
+// form it was given.
+func writeDirective(w *bufio.Writer, directive abc.Directive) {
+	text := directive.Name
+	if directive.Value != "" {
+		text += " " + directive.Value
+	}
+	if directive.Field {
+		writeField(w, 'I', text)
+		return
+	}
+	fmt.Fprintf(w, "%%%%%s\n", text)
+}
+
+// writeBars writes a sequence of bars as lines of music, each followed by
+// its lyrics. A bar starting a section or part or holding directives
+// starts a new line, preceded by the T: and P: fields and directives. The meter gives the
+// defaults for tuplets until the bars change it.
+func writeBars(w *bufio.Writer, meter abc.Meter, bars []abc.Bar) {
+	var line []abc.Bar
+	var previous abc.BarLine
+	for _, bar := range bars {
+		if bar.Title != "" || bar.Part != "" || len(bar.Directives) > 0 || len(line) == barsPerLine {
+			previous = writeLine(w, &meter, line, previous)
+			line = nil
+		}
+		if bar.Title != "" {
+			writeField(w, 'T', bar.Title)
+		}
+		if bar.Part != "" {
+			writeField(w, 'P', bar.Part)
+		}
+		for _, directive := range bar.Directives {
+			writeDirective(w, directive)
+		}
+		line = append(line, bar)
+	}
+	writeLine(w, &meter, line, previous)
+}
+
+// writeLine writes a single line of music following the given bar line,
+// and returns the bar line ending it. Adjacent bars share a bar line, so
+// the left bar line of a bar is only written where it differs from the
+// right bar line of the bar before.
+func writeLine(w *bufio.Writer, meter *abc.Meter, bars []abc.Bar, previous abc.BarLine) abc.BarLine {
+	if len(bars) == 0 {
+		return previous
+	}
+	var text strings.Builder
+	for i, bar := range bars {
+		if i > 0 {
+			text.WriteString(" ")
+		}
+		if left := formatBarLine(bar.Left); left != formatBarLine(previous) {
+			text.WriteString(left)
+			text.WriteString(" ")
+		}
+		text.WriteString(formatNotations(bar.Notation, meter))
+		text.WriteString(formatBarLine(bar.Right))
+		previous = bar.Right
+	}
+	line := text.String()
+	if line == "" {
+		// A bar holding only directives, after the end of the music
+		return previous
+	}
+	if len(line) > 1 && isLetter(line[0]) && line[1] == ':' {
+		// Music such as z:| would otherwise be read as a field
+		line = " " + line
+	}
+	w.WriteString(line)
+	w.WriteString("\n")
+	writeLyrics(w, bars)
+	return previous
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// writeLyrics writes a w: field for each verse sung to a line of music.
+func writeLyrics(w *bufio.Writer, bars []abc.Bar) {
+	var lyrics [][]abc.Syllable
+	verses := 0
+	for _, bar := range bars {
+		lyrics = appendLyrics(lyrics, bar.Notation)
+	}
+	for _, syllables := range lyrics {
+		if len(syllables) > verses {
+			verses = len(syllables)
+		}
+	}
+
+	for verse := 0; verse < verses; verse++ {
+		var line strings.Builder
+		for _, syllables := range lyrics {
+			syllable := abc.Syllable{}
+			if verse < len(syllables) {
+				syllable = syllables[verse]
+			}
+			line.WriteString(formatSyllable(syllable))
+		}
+		// Notes at the end of the line need not be skipped explicitly
+		text := strings.TrimRight(line.String(), "* ")
+		writeField(w, 'w', text)
+	}
+}
+
+// appendLyrics appends the syllables sung to each note and chord in
+// notation, including those within tuplets.
+func appendLyrics(lyrics [][]abc.Syllable, notation []abc.Notation) [][]abc.Syllable {
+	for _, n := range notation {
+		switch v := n.(type) {
+		case abc.Note:
+			lyrics = append(lyrics, v.Lyrics)
+		case abc.Chord:
+			lyrics = append(lyrics, v.Lyrics)
+		case abc.Tuplet:
+			lyrics = appendLyrics(lyrics, v.Notation)
+		}
+	}
+	return lyrics
+}
+
+// formatSyllable formats a single syllable of a lyric, along with the
+// separator that follows it.
+func formatSyllable(syllable abc.Syllable) string {
+	switch {
+	case syllable.Hold:
+		return "_ "
+	case syllable.Hyphen:
+		return escapeSyllable(syllable.Text) + "-"
+	case syllable.Text == "":
+		return "* "
+	}
+	return escapeSyllable(syllable.Text) + " "
+}
+
+var syllableEscaper = strings.NewReplacer(
+	" ", "~",
+	"-", "\\-",
+	"_", "\\_",
+	"*", "\\*",
+	"~", "\\~",
+	"|", "\\|",
+)
+
+func escapeSyllable(text string) string {
+	return syllableEscaper.Replace(text)
+}
+
+var barLineText = map[abc.BarLineType]string{
+	abc.BarLineSingle:         "|",
+	abc.BarLineDotted:         ".|",
+	abc.BarLineThinThin:       "||",
+	abc.BarLineThinThick:      "|]",
+	abc.BarLineThickThin:      "[|",
+	abc.BarLineStartRepeat:    "|:",
+	abc.BarLineEndRepeat:      ":|",
+	abc.BarLineStartEndRepeat: "::",
+}
+
+func formatBarLine(barLine abc.BarLine) string {
+	text := formatChordSymbols(barLine.ChordSymbols)
+	// A dot before a bar line would read as a dotted bar line, so every
+	// decoration is written by name
+	for _, decoration := range barLine.Decorations {
+		text += "!" + string(decoration) + "!"
+	}
+	text += barLineText[barLine.Type]
+	switch {
+	case len(barLine.Variants) == 1 && barLine.Type != abc.BarLineNone:
+		text += strconv.Itoa(barLine.Variants[0])
+	case len(barLine.Variants) > 0:
+		variants := make([]string, len(barLine.Variants))
+		for i, v := range barLine.Variants {
+			variants[i] = strconv.Itoa(v)
+		}
+		text += "[" + strings.Join(variants, ",")
+	}
+	return text
+}
+
+// formatNotations formats a sequence of notation, such as the contents
+// of a bar, updating meter with any change of meter it contains.
+func formatNotations(notation []abc.Notation, meter *abc.Meter) string {
+	text, _ := formatBroken(notation, meter, 0)
+	return text
+}
+
+// formatBroken formats a sequence of notation following one with the given
+// broken rhythm, returning the broken rhythm of the last. A broken rhythm
+// may continue into or out of a tuplet, so tuplets are formatted here
+// rather than by formatNotation.
+func formatBroken(notation []abc.Notation, meter *abc.Meter, broken abc.BrokenRhythm) (string, abc.BrokenRhythm) {
+	var text strings.Builder
+	for _, n := range notation {
+		if beamBreak(n) {
+			text.WriteString(" ")
+		}
+		if tuplet, ok := n.(abc.Tuplet); ok {
+			var inner string
+			inner, broken = formatBroken(tuplet.Notation, meter, broken)
+			text.WriteString(formatTuplet(tuplet, *meter))
+			text.WriteString(inner)
+			continue
+		}
+		if change, ok := n.(abc.MeterChange); ok {
+			*meter = change.Meter
+		}
+		n, broken = writtenLength(n, broken)
+		text.WriteString(formatNotation(n))
+		switch n.(type) {
+		case abc.Note, abc.Chord:
+			text.WriteString(broken.String())
+		}
+	}
+	return text.String(), broken
+}
+
+// beamBreak reports whether n is separated by a space from the notation
+// before it.
+func beamBreak(n abc.Notation) bool {
+	switch v := n.(type) {
+	case abc.Note:
+		return v.BeamBreak
+	case abc.Rest:
+		return v.BeamBreak
+	case abc.Chord:
+		return v.BeamBreak
+	case abc.GraceNotes:
+		return v.BeamBreak
+	case abc.Tuplet:
+		return v.BeamBreak
+	}
+	return false
+}
+
+// writtenLength undoes the broken rhythms applied to the duration of n,
+// both its own and the one following the previous notation, returning the
+// notation with the length it is written with and its own broken rhythm.
+func writtenLength(n abc.Notation, previous abc.BrokenRhythm) (abc.Notation, abc.BrokenRhythm) {
+	_, after := previous.Factors()
+	switch v := n.(type) {
+	case abc.Note:
+		if previous != 0 || v.Broken != 0 {
+			before, _ := v.Broken.Factors()
+			v.Duration = v.Duration.Mul(inverse(after)).Mul(inverse(before))
+		}
+		return v, v.Broken
+	case abc.Chord:
+		if previous != 0 || v.Broken != 0 {
+			before, _ := v.Broken.Factors()
+			v.Duration = v.Duration.Mul(inverse(after)).Mul(inverse(before))
+		}
+		return v, v.Broken
+	case abc.GraceNotes, abc.KeyChange, abc.MeterChange, abc.NoteLengthChange, abc.TempoChange:
+		// Grace notes and changes of key, meter, unit note length or
+		// tempo fall between the notes of a broken rhythm
+		return v, previous
+	}
+	return n, 0
+}
+
+// inverse returns 1/d.
+func inverse(d abc.Duration) abc.Duration {
+	return abc.Duration{Numerator: d.Denominator, Denominator: d.Numerator}
+}
+
+func formatNotation(n abc.Notation) string {
+	switch v := n.(type) {
+	case abc.Note:
+		return formatNote(v)
+	case abc.Rest:
+		prefix := formatChordSymbols(v.ChordSymbols) + formatDecorations(v.Decorations)
+		if v.Invisible {
+			return prefix + "x" + formatDuration(v.Duration)
+		}
+		return prefix + "z" + formatDuration(v.Duration)
+	case abc.MultiMeasureRest:
+		if v.Bars == 1 {
+			return "Z"
+		}
+		return "Z" + strconv.Itoa(v.Bars)
+	case abc.Chord:
+		var text strings.Builder
+		text.WriteString(formatSlurs(v.Slurs))
+		text.WriteString(formatChordSymbols(v.ChordSymbols))
+		text.WriteString(formatDecorations(v.Decorations))
+		text.WriteString("[")
+		for _, note := range v.Notes {
+			text.WriteString(formatNote(note))
+		}
+		text.WriteString("]")
+		text.WriteString(formatDuration(v.Duration))
+		text.WriteString(strings.Repeat(")", len(v.SlursEnded)))
+		return text.String()
+	case abc.GraceNotes:
+		var text strings.Builder
+		text.WriteString("{")
+		if v.Acciaccatura {
+			text.WriteString("/")
+		}
+		for _, note := range v.Notes {
+			text.WriteString(formatNote(note))
+		}
+		text.WriteString("}")
+		return text.String()
+	case abc.KeyChange:
+		if v.Key == nil {
+			return "[K:" + strings.Join(formatClef(v.Clef), " ") + "]"
+		}
+		return "[K:" + formatKey(*v.Key, v.Clef) + "]"
+	case abc.MeterChange:
+		return "[M:" + formatMeter(v.Meter) + "]"
+	case abc.NoteLengthChange:
+		return "[L:" + formatFraction(v.NoteLength) + "]"
+	case abc.TempoChange:
+		return "[Q:" + formatTempo(v.Tempo) + "]"
+	}
+	return ""
+}
+
+// formatTuplet formats the start of a tuplet, leaving out q and r where
+// they take their defaults.
+func formatTuplet(tuplet abc.Tuplet, meter abc.Meter) string {
+	text := "(" + strconv.Itoa(tuplet.P)
+	q := ""
+	if tuplet.Q != meter.TupletTime(tuplet.P) {
+		q = strconv.Itoa(tuplet.Q)
+	}
+	if tuplet.R != tuplet.P {
+		return text + ":" + q + ":" + strconv.Itoa(tuplet.R)
+	}
+	if q != "" {
+		return text + ":" + q
+	}
+	return text
+}
+
+var accidentalText = map[abc.Accidental]string{
+	abc.AccidentalSharp:       "^",
+	abc.AccidentalFlat:        "_",
+	abc.AccidentalNatural:     "=",
+	abc.AccidentalDoubleSharp: "^^",
+	abc.AccidentalDoubleFlat:  "__",
+}
+
+func formatNote(note abc.Note) string {
+	accidental := accidentalText[note.Accidental]
+	if note.Microtone.Denominator != 0 {
+		accidental += fmt.Sprintf("%d/%d", note.Microtone.Numerator, note.Microtone.Denominator)
+	}
+	return formatSlurs(note.Slurs) + formatChordSymbols(note.ChordSymbols) + formatDecorations(note.Decorations) + accidental + formatPitch(note.Pitch, note.Octave) +
+		formatDuration(note.Duration) + tieText[note.Tie] + strings.Repeat(")", len(note.SlursEnded))
+}
+
+// formatDecorations formats the decorations preceding a note, chord or
+// rest. Staccato is written as a dot, its usual form.
+func formatDecorations(decorations []abc.Decoration) string {
+	var text strings.Builder
+	for _, decoration := range decorations {
+		if decoration == abc.DecorationStaccato {
+			text.WriteString(".")
+			continue
+		}
+		text.WriteString("!" + string(decoration) + "!")
+	}
+	return text.String()
+}
+
+// formatChordSymbols formats the chord symbols preceding a note, chord or
+// rest, each in quotes.
+func formatChordSymbols(symbols []abc.ChordSymbol) string {
+	var text strings.Builder
+	for _, symbol := range symbols {
+		text.WriteString(`"` + formatChordSymbol(symbol) + `"`)
+	}
+	return text.String()
+}
+
+var chordQualityText = map[abc.ChordQuality]string{
+	abc.ChordMinor:             "m",
+	abc.ChordDominant:          "7",
+	abc.ChordMajorSeventh:      "maj7",
+	abc.ChordMinorSeventh:      "m7",
+	abc.ChordHalfDiminished:    "m7b5",
+	abc.ChordDiminished:        "dim",
+	abc.ChordDiminishedSeventh: "dim7",
+	abc.ChordAugmented:         "aug",
+	abc.ChordSuspendedSecond:   "sus2",
+	abc.ChordSuspendedFourth:   "sus4",
+	abc.ChordPower:             "5",
+}
+
+// formatChordSymbol formats a chord symbol such as Am7/G. Numbers that
+// would otherwise run together are separated by a slash, as in C6/9.
+func formatChordSymbol(symbol abc.ChordSymbol) string {
+	if symbol.Root == 0 {
+		return symbol.Text
+	}
+	quality := chordQualityText[symbol.Quality]
+	extensions := symbol.Extensions
+	if len(extensions) > 0 && strings.HasSuffix(quality, "7") && symbol.Quality != abc.ChordHalfDiminished {
+		switch extensions[0] {
+		case "9", "11", "13":
+			// Such as C9, which implies the seventh
+			quality = strings.TrimSuffix(quality, "7") + extensions[0]
+			extensions = extensions[1:]
+		}
+	}
+
+	text := chordRootText(symbol.Root, symbol.Accidental) + quality
+	for _, extension := range extensions {
+		if endsWithDigit(text) && extension[0] >= '0' && extension[0] <= '9' {
+			text += "/"
+		}
+		text += extension
+	}
+	if symbol.Bass != 0 {
+		text += "/" + chordRootText(symbol.Bass, symbol.BassAccidental)
+	}
+	return text
+}
+
+// chordRootText formats the root or bass note of a chord symbol.
+func chordRootText(pitch abc.Pitch, accidental abc.Accidental) string {
+	switch accidental {
+	case abc.AccidentalSharp:
+		return string(pitch) + "#"
+	case abc.AccidentalFlat:
+		return string(pitch) + "b"
+	}
+	return string(pitch)
+}
+
+// endsWithDigit reports whether text ends with a digit.
+func endsWithDigit(text string) bool {
+	return text != "" && text[len(text)-1] >= '0' && text[len(text)-1] <= '9'
+}
+
+// formatUserSymbol formats the definition of a symbol by a U: field.
+func formatUserSymbol(symbol abc.UserSymbol) string {
+	if symbol.Decoration == "" {
+		return symbol.Symbol + " = !nil!"
+	}
+	return symbol.Symbol + " = !" + string(symbol.Decoration) + "!"
+}
+
+var tieText = map[abc.Tie]string{
+	abc.TieSolid:  "-",
+	abc.TieDotted: ".-",
+}
+
+// formatSlurs formats the starts of slurs, which precede the note or
+// chord they start at.
+func formatSlurs(slurs []abc.Slur) string {
+	var text strings.Builder
+	for _, slur := range slurs {
+		if slur.Dotted {
+			text.WriteString(".")
+		}
+		text.WriteString("(")
+	}
+	return text.String()
+}
+
+// formatPitch formats a pitch in the given octave, using lower case and
+// octave marks as needed.
+func formatPitch(pitch abc.Pitch, octave int) string {
+	if octave > 0 {
+		return strings.ToLower(string(pitch)) + strings.Repeat("'", octave-1)
+	}
+	return string(pitch) + strings.Repeat(",", -octave)
+}
+
+// formatDuration formats the length of a note relative to the unit note
+// length, omitting it entirely for a single unit.
+func formatDuration(duration abc.NoteLength) string {
+	switch {
+	case duration.Denominator == 0 || duration.Numerator == duration.Denominator:
+		return ""
+	case duration.Denominator == 1:
+		return strconv.Itoa(duration.Numerator)
+	case duration.Numerator == 1:
+		return "/" + strconv.Itoa(duration.Denominator)
+	}
+	return formatFraction(duration)
+}
+
+func formatFraction(length abc.NoteLength) string {
+	return fmt.Sprintf("%d/%d", length.Numerator, length.Denominator)
+}
+
+// formatMeter formats a meter, returning an empty string if no meter was
+// given.
+func formatMeter(meter abc.Meter) string {
+	switch meter.Type {
+	case abc.MeterCommon:
+		return "C"
+	case abc.MeterCut:
+		return "C|"
+	case abc.MeterFree:
+		return "none"
+	}
+	if meter.Denominator == 0 {
+		return ""
+	}
+	numerator := make([]string, len(meter.Numerator))
+	for i, n := range meter.Numerator {
+		numerator[i] = strconv.Itoa(n)
+	}
+	if meter.Grouped {
+		return fmt.Sprintf("(%s)/%d", strings.Join(numerator, "+"), meter.Denominator)
+	}
+	return fmt.Sprintf("%s/%d", strings.Join(numerator, "+"), meter.Denominator)
+}
+
+func formatTempo(tempo abc.Tempo) string {
+	var fields []string
+	if tempo.Text != "" {
+		fields = append(fields, quote(tempo.Text))
+	}
+	for _, beat := range tempo.Beat {
+		fields = append(fields, formatFraction(beat))
+	}
+	if tempo.BPM != 0 {
+		if len(tempo.Beat) > 0 {
+			fields[len(fields)-1] += "=" + strconv.Itoa(tempo.BPM)
+		} else {
+			fields = append(fields, strconv.Itoa(tempo.BPM))
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func formatPlayOrder(order abc.PlayOrder) string {
+	var text strings.Builder
+	for _, item := range order {
+		if item.Group != nil {
+			text.WriteString("(" + formatPlayOrder(item.Group) + ")")
+		} else {
+			text.WriteString(item.Part)
+		}
+		if item.Repeat > 1 {
+			text.WriteString(strconv.Itoa(item.Repeat))
+		}
+	}
+	return text.String()
+}
+
+var modeText = map[abc.Mode]string{
+	abc.ModeMajor:      "",
+	abc.ModeMinor:      "m",
+	abc.ModeIonian:     "Ion",
+	abc.ModeAeolian:    "Aeo",
+	abc.ModeMixolydian: "Mix",
+	abc.ModeDorian:     "Dor",
+	abc.ModePhrygian:   "Phr",
+	abc.ModeLydian:     "Lyd",
+	abc.ModeLocrian:    "Loc",
+}
+
+func formatKey(key abc.Key, clef abc.Clef) string {
+	var fields []string
+	switch key.Type {
+	case abc.KeyNone:
+		fields = append(fields, "none")
+	case abc.KeyHighlandPipes:
+		fields = append(fields, "HP")
+	case abc.KeyHighlandPipesSignature:
+		fields = append(fields, "Hp")
+	default:
+		tonic := key.Tonic
+		if tonic == 0 {
+			tonic = 'C'
+		}
+		text := string(tonic)
+		switch key.Accidental {
+		case abc.AccidentalSharp:
+			text += "#"
+		case abc.AccidentalFlat:
+			text += "b"
+		}
+		fields = append(fields, text+modeText[key.Mode])
+	}
+	if key.Explicit {
+		fields = append(fields, "exp")
+	}
+	for _, accidental := range key.Accidentals {
+		fields = append(fields, accidentalText[accidental.Accidental]+strings.ToLower(string(accidental.Pitch)))
+	}
+	fields = append(fields, formatClef(clef)...)
+	return strings.Join(fields, " ")
+}
+
+var clefText = map[abc.ClefType]string{
+	abc.ClefTreble:     "treble",
+	abc.ClefAlto:       "alto",
+	abc.ClefTenor:      "tenor",
+	abc.ClefBass:       "bass",
+	abc.ClefPercussion: "perc",
+	abc.ClefNone:       "none",
+}
+
+// formatClef returns the modifiers describing a clef.
+func formatClef(clef abc.Clef) []string {
+	var fields []string
+	if clef.Type != abc.ClefDefault {
+		text := "clef=" + clefText[clef.Type]
+		if clef.Line != 0 {
+			text += strconv.Itoa(clef.Line)
+		}
+		switch clef.OctaveMark {
+		case 1:
+			text += "+8"
+		case -1:
+			text += "-8"
+		}
+		fields = append(fields, text)
+	}
+	if clef.Middle.Pitch != 0 {
+		fields = append(fields, "middle="+formatPitch(clef.Middle.Pitch, clef.Middle.Octave))
+	}
+	if clef.Transpose != 0 {
+		fields = append(fields, "transpose="+strconv.Itoa(clef.Transpose))
+	}
+	if clef.Octave != 0 {
+		fields = append(fields, "octave="+strconv.Itoa(clef.Octave))
+	}
+	if clef.StaffLines != 0 {
+		fields = append(fields, "stafflines="+strconv.Itoa(clef.StaffLines))
+	}
+	return fields
+}
+
+var stemText = map[abc.Stem]string{
+	abc.StemUp:   "up",
+	abc.StemDown: "down",
+}
+
+func formatVoice(voice abc.Voice) string {
+	fields := []string{voice.ID}
+	if voice.Name != "" {
+		fields = append(fields, "name="+quote(voice.Name))
+	}
+	if voice.Subname != "" {
+		fields = append(fields, "subname="+quote(voice.Subname))
+	}
+	if voice.Stem != abc.StemAuto {
+		fields = append(fields, "stem="+stemText[voice.Stem])
+	}
+	fields = append(fields, formatClef(voice.Clef)...)
+	return strings.Join(fields, " ")
+}
+
+// quote encloses text in double quotes. ABC provides no means of escaping
+// quotes within the text.
+func quote(text string) string {
+	return `"` + text + `"`
+}
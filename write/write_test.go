@@ -0,0 +1,175 @@
+package write
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/theothertomelliott/abc"
+	"github.com/theothertomelliott/abc/parse"
+)
+
+func TestWrite(t *testing.T) {
+	note := func(pitch abc.Pitch, octave int, numerator, denominator int) abc.Note {
+		return abc.Note{
+			Pitch:    pitch,
+			Octave:   octave,
+			Duration: abc.NoteLength{Numerator: numerator, Denominator: denominator},
+		}
+	}
+	tunes := []abc.Tune{
+		{
+			Sequence:   1,
+			Title:      "Example",
+			Composer:   "Trad.",
+			Meter:      abc.Meter{Numerator: []int{6}, Denominator: 8},
+			NoteLength: abc.NoteLength{Numerator: 1, Denominator: 8},
+			Tempo: abc.Tempo{
+				Beat: []abc.NoteLength{{Numerator: 3, Denominator: 8}},
+				BPM:  100,
+				Text: "Lively",
+			},
+			Key:            abc.Key{Tonic: 'E', Mode: abc.ModeDorian},
+			Clef:           abc.Clef{Type: abc.ClefTreble, OctaveMark: -1},
+			History:        []string{"First line", "Second line"},
+			WordsAfterTune: []string{"The end"},
+			Bars: []abc.Bar{
+				{
+					Left: abc.BarLine{Type: abc.BarLineStartRepeat},
+					Notation: []abc.Notation{
+						abc.Note{Pitch: 'E', Duration: abc.NoteLength{Numerator: 1, Denominator: 1}, Lyrics: []abc.Syllable{{Text: "Hel", Hyphen: true}}},
+						abc.Note{Pitch: 'F', Accidental: abc.AccidentalSharp, Duration: abc.NoteLength{Numerator: 1, Denominator: 2}, Lyrics: []abc.Syllable{{Text: "lo"}}},
+						abc.Rest{Duration: abc.NoteLength{Numerator: 1, Denominator: 2}},
+						abc.Chord{
+							Notes:    []abc.Note{note('G', 0, 1, 1), note('B', 0, 1, 1)},
+							Duration: abc.NoteLength{Numerator: 3, Denominator: 2},
+							Lyrics:   []abc.Syllable{{Hold: true}},
+						},
+						note('D', 2, 1, 1),
+					},
+					Right: abc.BarLine{Type: abc.BarLineSingle, Variants: []int{1}},
+				},
+				{
+					Left:     abc.BarLine{Type: abc.BarLineSingle, Variants: []int{1}},
+					Notation: []abc.Notation{note('A', -1, 6, 1)},
+					Right:    abc.BarLine{Type: abc.BarLineEndRepeat},
+				},
+				{
+					Part:     "B",
+					Left:     abc.BarLine{Type: abc.BarLineEndRepeat},
+					Notation: []abc.Notation{abc.MultiMeasureRest{Bars: 2}},
+					Right:    abc.BarLine{Type: abc.BarLineThinThick},
+				},
+			},
+		},
+	}
+	expected := `X:1
+T:Example
+C:Trad.
+M:6/8
+L:1/8
+Q:"Lively" 3/8=100
+H:First line
+H:Second line
+K:EDor clef=treble-8
+|: E^F/2z/2[GB]3/2d'|1 A,6:|
+w:Hel-lo _
+P:B
+Z2|]
+W:The end
+`
+	var out bytes.Buffer
+	if err := Write(&out, tunes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := out.String(); got != expected {
+		t.Errorf("output did not match: %v", cmp.Diff(expected, got))
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	input := `%%pagewidth 21cm
+H:From the file header
+
+X:1
+T:Round trip
+C:Composer name
+O:Irish
+R:Reel
+M:4+1/4
+L:1/8
+Q:1/4=120
+P:A2B
+B:O'Neills
+N:A comment
+S:collected in Brittany
+Z:John Smith
+I:linebreak $
+K:D exp ^f _b
+P:A
+|:DE^FG [Ace]2 z2|[1 A4 x4:|[2 A8|]
+w:one two-three four five
+%%vskip 1cm
+P:B
+I:score 1
+Z4|]
+%%newpage
+
+X:2
+T:Voices
+M:(2+3+2)/8
+V:1 name="Upper" stem=up
+V:2 clef=bass transpose=-12
+U:T = !turn!
+U:h = !accent!
+U:~ = !nil!
+K:Am
+V:1
+ABc'd,|^^e2 __e2 ^3/4e2 e/e//e//|A>B c2<<d [ce]>>f g|(3ABc (3::2de (5:3:4(3ABcdef|{g}A{/ge}B>{f}c (3{A}Bcd|
+(A-A .(B2 [ce]-|[ce]) ((3ABc.-)c)|
+~A .B !segno!| !p!!crescendo(![ce] !fermata!z !<)!Tc uv!f!|h~A|
+[CE]<(3:2:2AB (3A>Bc>d|
+c'/<[V:2]bb|[V:1]d2|
+"Am7/G"A "F#m7b5"B "C9"c "Bbmaj9"d "C6/9"e "G7(b9,#11)"f "Dsus4"[DA] "E5"z "N.C."z "Fine"|]
+w:one two three
+V:2
+A4 [K:none]E4|[M:3/4][L:1/4]A3|]
+W:Words after the tune
+
+X:3
+T:Repeats
+K:G
+|:GAG GAB:|
+|:ABA ABd:|
+abcd|abcd|z::A:|abcd|ab[I:MIDI transpose 2]cd|A:|
+K:D
+abcd. |:ABcd!trill!. |]
+d>[K:Bb clef=bass]e [K:clef=treble]f|
+M:9/8
+(5ABcde [L:1/16]f>[M:C|]g (5ABcde|
+Q:"Slower" 1/4=90
+A>[Q:60]B|
+T:Coda
+P:C
+GABc|
+
+X:4
+T:No key
+`
+	first, err := parse.Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error parsing input: %v", err)
+	}
+	var out bytes.Buffer
+	if err := Write(&out, first); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	second, err := parse.Read(&out)
+	if err != nil {
+		t.Fatalf("unexpected error parsing output: %v", err)
+	}
+	if !cmp.Equal(first, second) {
+		t.Errorf("tunes did not match: %v", cmp.Diff(first, second))
+	}
+}